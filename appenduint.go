@@ -0,0 +1,65 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package faf
+
+// AppendUint appends the decimal representation of the specified unsigned
+// number to the given byte slice, returning the extended byte slice. As long as
+// the byte slice has sufficient capacity, AppendUint does not allocate.
+//
+// AppendUint is the counterpart to [ParseUint] and mainly intended for building
+// procfs paths, such as “/proc/$PID/stat”, without first having to allocate a
+// string using [strconv.Itoa] and then concatenating strings.
+func AppendUint(b []byte, num uint64) []byte {
+	var digits [20]byte // 18446744073709551615 has 20 digits.
+	pos := len(digits)
+	for num >= 10 {
+		pos--
+		digits[pos] = byte('0' + num%10)
+		num /= 10
+	}
+	pos--
+	digits[pos] = byte('0' + num)
+	return append(b, digits[pos:]...)
+}
+
+const hexdigits = "0123456789abcdef"
+
+// AppendHexUint appends the hexadecimal representation of the specified
+// unsigned number to the given byte slice, returning the extended byte slice.
+// AppendHexUint uses lower case hex digits “a” to “f” and doesn't prepend any
+// “0x” prefix. As long as the byte slice has sufficient capacity, AppendHexUint
+// does not allocate.
+func AppendHexUint(b []byte, num uint64) []byte {
+	var digits [16]byte
+	pos := len(digits)
+	for num >= 16 {
+		pos--
+		digits[pos] = hexdigits[num&0xf]
+		num >>= 4
+	}
+	pos--
+	digits[pos] = hexdigits[num]
+	return append(b, digits[pos:]...)
+}
+
+// AppendInt appends the decimal representation of the specified signed number
+// to the given byte slice, returning the extended byte slice. As long as the
+// byte slice has sufficient capacity, AppendInt does not allocate.
+func AppendInt(b []byte, num int64) []byte {
+	if num < 0 {
+		return AppendUint(append(b, '-'), uint64(-num))
+	}
+	return AppendUint(b, uint64(num))
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package faf
+
+import (
+	"math"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("appending uint64s", func() {
+
+	DescribeTable("decimal",
+		func(num uint64) {
+			Expect(string(AppendUint([]byte("foo"), num))).To(
+				Equal("foo" + strconv.FormatUint(num, 10)))
+		},
+		Entry(nil, uint64(0)),
+		Entry(nil, uint64(7)),
+		Entry(nil, uint64(42)),
+		Entry(nil, uint64(1234567890)),
+		Entry(nil, uint64(math.MaxUint64)),
+	)
+
+	DescribeTable("hexadecimal",
+		func(num uint64) {
+			Expect(string(AppendHexUint([]byte("foo"), num))).To(
+				Equal("foo" + strconv.FormatUint(num, 16)))
+		},
+		Entry(nil, uint64(0)),
+		Entry(nil, uint64(0xf)),
+		Entry(nil, uint64(0x42)),
+		Entry(nil, uint64(0xdeadbeefcafe)),
+		Entry(nil, uint64(math.MaxUint64)),
+	)
+
+	DescribeTable("signed decimal",
+		func(num int64) {
+			Expect(string(AppendInt([]byte("foo"), num))).To(
+				Equal("foo" + strconv.FormatInt(num, 10)))
+		},
+		Entry(nil, int64(0)),
+		Entry(nil, int64(42)),
+		Entry(nil, int64(-1000)),
+		Entry(nil, int64(math.MaxInt64)),
+		Entry(nil, int64(math.MinInt64)),
+	)
+
+	It("round trips", func() {
+		Expect(Ok(ParseUint(AppendUint(nil, 666)))).To(Equal(uint64(666)))
+		Expect(Ok(ParseHexUint(AppendHexUint(nil, 0xc0ffee)))).To(Equal(uint64(0xc0ffee)))
+	})
+
+})
@@ -126,6 +126,32 @@ func (b *Bytestring) Uint64() (num uint64, ok bool) {
 	}
 }
 
+// Int64 parses the decimal number with an optional leading “-” sign starting
+// in the buffer at the current position until a character other than 0-9 is
+// encountered, or EOL. The number must consist of at least a single digit. If
+// successful, Int64 returns the number and true; otherwise zero and false,
+// leaving the current position unchanged. Numbers outside the int64 range are
+// also considered to be an error, returning zero and false in this case.
+func (b *Bytestring) Int64() (num int64, ok bool) {
+	pos := b.pos
+	neg := b.pos < len(b.b) && b.b[b.pos] == '-'
+	if neg {
+		b.pos++
+	}
+	unum, ok := b.Uint64()
+	switch {
+	case !ok:
+		b.pos = pos
+		return 0, false
+	case neg && unum <= 1<<63:
+		return -int64(unum), true
+	case !neg && unum < 1<<63:
+		return int64(unum), true
+	}
+	b.pos = pos
+	return 0, false
+}
+
 const cutoffHexUint64 = 1 << 60
 
 // HexUint64 parses the hexadecimal number starting in the buffer at the current
@@ -199,3 +225,65 @@ func (b *Bytestring) NumFields() (num int) {
 		}
 	}
 }
+
+// LinkTarget parses a link target of the form “kind:[inode]” starting at the
+// current position until EOL, as found in procfs for namespace links, such as
+// “net:[4026531840]”, and for file descriptor links, such as “socket:[12345]”
+// and “pipe:[67890]”. If successful, LinkTarget returns the kind, the inode
+// number, and true.
+//
+// Additionally, LinkTarget also accepts link targets of anonymous inodes, such
+// as “anon_inode:[eventfd]” and “anon_inode:inotify”. In this case, LinkTarget
+// returns the kind, a zero inode number, the name of the anonymous inode, and
+// true.
+//
+// Otherwise, LinkTarget returns false and the buffer's parsing position is
+// left unchanged. The kind and name returned reference the underlying byte
+// string.
+func (b *Bytestring) LinkTarget() (kind []byte, ino uint64, name []byte, ok bool) {
+	start := b.pos
+	pos := start
+	for {
+		if pos >= len(b.b) {
+			return nil, 0, nil, false
+		}
+		ch := b.b[pos]
+		if ch == ':' {
+			break
+		}
+		if ch == '/' || ch == ' ' || ch == '[' || ch == ']' {
+			return nil, 0, nil, false
+		}
+		pos++
+	}
+	if pos == start {
+		return nil, 0, nil, false
+	}
+	kind = b.b[start:pos]
+	pos++ // skip ":"
+	if pos >= len(b.b) {
+		return nil, 0, nil, false
+	}
+	if b.b[pos] != '[' {
+		// only anonymous inodes might lack the brackets, such as in
+		// "anon_inode:inotify".
+		if string(kind) != "anon_inode" {
+			return nil, 0, nil, false
+		}
+		b.pos = len(b.b)
+		return kind, 0, b.b[pos:], true
+	}
+	if b.b[len(b.b)-1] != ']' || pos+2 > len(b.b)-1 {
+		return nil, 0, nil, false
+	}
+	inner := b.b[pos+1 : len(b.b)-1]
+	if ino, ok := ParseUint(inner); ok {
+		b.pos = len(b.b)
+		return kind, ino, nil, true
+	}
+	if string(kind) != "anon_inode" {
+		return nil, 0, nil, false
+	}
+	b.pos = len(b.b)
+	return kind, 0, inner, true
+}
@@ -17,6 +17,7 @@ package faf
 import (
 	"fmt"
 	"math"
+	"strconv"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -136,6 +137,43 @@ var _ = Describe("byteline", func() {
 		})
 	})
 
+	When("parsing signed decimal numbers", func() {
+
+		It("requires at least one digit", func() {
+			for _, s := range []string{"", "-", "-foo", "foo"} {
+				bstr := NewBytestring([]byte(s))
+				_, ok := bstr.Int64()
+				Expect(ok).To(BeFalse(), "%q", s)
+				Expect(bstr.pos).To(Equal(0))
+			}
+		})
+
+		DescribeTable("returns correct numbers",
+			func(s string, expected int64, pos int) {
+				bstr := NewBytestring([]byte(s))
+				Expect(Ok(bstr.Int64())).To(Equal(expected))
+				Expect(bstr.pos).To(Equal(pos))
+			},
+			Entry(nil, "0", int64(0), 1),
+			Entry(nil, "-0", int64(0), 2),
+			Entry(nil, "42foo", int64(42), 2),
+			Entry(nil, "-1000", int64(-1000), 5),
+			Entry(nil, strconv.FormatInt(math.MaxInt64, 10), int64(math.MaxInt64), 19),
+			Entry(nil, strconv.FormatInt(math.MinInt64, 10), int64(math.MinInt64), 20),
+		)
+
+		It("rejects numbers outside the int64 range", func() {
+			for _, s := range []string{"9223372036854775808", "-9223372036854775809"} {
+				bstr := NewBytestring([]byte(s))
+				v, ok := bstr.Int64()
+				Expect(ok).To(BeFalse(), "%q", s)
+				Expect(v).To(BeZero())
+				Expect(bstr.pos).To(Equal(0))
+			}
+		})
+
+	})
+
 	When("parsing hex numbers", func() {
 
 		It("requires at least one digit", func() {
@@ -207,4 +245,49 @@ var _ = Describe("byteline", func() {
 
 	})
 
+	When("parsing link targets", func() {
+
+		DescribeTable("returns kind and inode number or name",
+			func(s string, kind string, ino uint64, name string) {
+				bstr := NewBytestring([]byte(s))
+				k, i, n, ok := bstr.LinkTarget()
+				Expect(ok).To(BeTrue())
+				Expect(string(k)).To(Equal(kind))
+				Expect(i).To(Equal(ino))
+				Expect(string(n)).To(Equal(name))
+				Expect(bstr.EOL()).To(BeTrue())
+			},
+			Entry(nil, "net:[4026531840]", "net", uint64(4026531840), ""),
+			Entry(nil, "pid_for_children:[4026531836]", "pid_for_children", uint64(4026531836), ""),
+			Entry(nil, "socket:[12345]", "socket", uint64(12345), ""),
+			Entry(nil, "pipe:[67890]", "pipe", uint64(67890), ""),
+			Entry(nil, "anon_inode:[eventfd]", "anon_inode", uint64(0), "eventfd"),
+			Entry(nil, "anon_inode:inotify", "anon_inode", uint64(0), "inotify"),
+		)
+
+		DescribeTable("rejects other things",
+			func(s string) {
+				bstr := NewBytestring([]byte(s))
+				k, i, n, ok := bstr.LinkTarget()
+				Expect(ok).To(BeFalse())
+				Expect(k).To(BeNil())
+				Expect(i).To(BeZero())
+				Expect(n).To(BeNil())
+				Expect(bstr.pos).To(BeZero())
+			},
+			Entry(nil, ""),
+			Entry(nil, "/dev/null"),
+			Entry(nil, "/tmp/foo:[42]"),
+			Entry(nil, ":[42]"),
+			Entry(nil, "net:"),
+			Entry(nil, "net:["),
+			Entry(nil, "net:[]"),
+			Entry(nil, "net:[42"),
+			Entry(nil, "net:42"),
+			Entry(nil, "net:[foo]"),
+			Entry(nil, "net [42]"),
+		)
+
+	})
+
 })
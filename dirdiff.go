@@ -0,0 +1,98 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"bytes"
+	"fmt"
+	"iter"
+)
+
+// DirChange describes how a directory entry changed between two directory
+// snapshots.
+type DirChange uint8
+
+const (
+	DirChangeAdded   DirChange = iota + 1 // entry is new
+	DirChangeRemoved                      // entry is gone
+	DirChangeInode                        // entry has same name, but different inode number
+)
+
+func (c DirChange) String() string {
+	switch c {
+	case DirChangeAdded:
+		return "added"
+	case DirChangeRemoved:
+		return "removed"
+	case DirChangeInode:
+		return "inode changed"
+	}
+	return fmt.Sprintf("DirChange(%d)", c)
+}
+
+// Diff returns an iterator over the differences between this (older) directory
+// snapshot and the specified newer directory snapshot. It yields added and
+// changed entries from the newer snapshot, and removed entries from this older
+// snapshot. An entry counts as changed when it has the same name in both
+// snapshots, but a different inode number, such as when a process exited and
+// its PID got reused in the meantime. Either snapshot can be nil, which is
+// taken as an empty snapshot.
+//
+// Diff first sorts both snapshots by name if not already sorted, and then
+// walks them in a merge-style algorithm, yielding the differences in byte-wise
+// lexical name order. Diff does not allocate per directory entry.
+func (s *DirSnapshot) Diff(newer *DirSnapshot) iter.Seq2[DirChange, DirEntry] {
+	return func(yield func(DirChange, DirEntry) bool) {
+		s.Sort()
+		newer.Sort()
+		oldIdx, oldLen := 0, s.Len()
+		newIdx, newLen := 0, newer.Len()
+		for oldIdx < oldLen && newIdx < newLen {
+			oldEntry := &s.entries[oldIdx]
+			newEntry := &newer.entries[newIdx]
+			switch bytes.Compare(s.name(oldEntry), newer.name(newEntry)) {
+			case -1:
+				if !yield(DirChangeRemoved, s.entry(oldEntry)) {
+					return
+				}
+				oldIdx++
+			case 1:
+				if !yield(DirChangeAdded, newer.entry(newEntry)) {
+					return
+				}
+				newIdx++
+			default:
+				if oldEntry.ino != newEntry.ino &&
+					!yield(DirChangeInode, newer.entry(newEntry)) {
+					return
+				}
+				oldIdx++
+				newIdx++
+			}
+		}
+		for ; oldIdx < oldLen; oldIdx++ {
+			if !yield(DirChangeRemoved, s.entry(&s.entries[oldIdx])) {
+				return
+			}
+		}
+		for ; newIdx < newLen; newIdx++ {
+			if !yield(DirChangeAdded, newer.entry(&newer.entries[newIdx])) {
+				return
+			}
+		}
+	}
+}
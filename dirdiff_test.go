@@ -0,0 +1,99 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("diffing directory snapshots", func() {
+
+	type change struct {
+		Change DirChange
+		Name   string
+	}
+
+	diff := func(older, newer *DirSnapshot) []change {
+		changes := []change{}
+		for ch, dentry := range older.Diff(newer) {
+			changes = append(changes, change{Change: ch, Name: string(dentry.Name)})
+		}
+		return changes
+	}
+
+	DescribeTable("DirChange",
+		func(c DirChange, expected string) {
+			Expect(c.String()).To(Equal(expected))
+		},
+		Entry(nil, DirChangeAdded, "added"),
+		Entry(nil, DirChangeRemoved, "removed"),
+		Entry(nil, DirChangeInode, "inode changed"),
+		Entry(nil, DirChange(42), "DirChange(42)"),
+	)
+
+	It("diffs snapshots", func() {
+		dir := GinkgoT().TempDir()
+		for _, name := range []string{"a", "c", "d", "e", "g"} {
+			Expect(os.WriteFile(filepath.Join(dir, name), nil, 0644)).To(Succeed())
+		}
+		older := SnapshotDir(dir)
+		Expect(older).NotTo(BeNil())
+
+		Expect(os.Remove(filepath.Join(dir, "a"))).To(Succeed())
+		Expect(os.Remove(filepath.Join(dir, "g"))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "b"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "h"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "i"), nil, 0644)).To(Succeed())
+		// replace "d" with a new file in order to get a new inode number, while
+		// the old inode number is still in use.
+		Expect(os.Rename(filepath.Join(dir, "d"), filepath.Join(dir, "x"))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "d"), nil, 0644)).To(Succeed())
+		newer := SnapshotDir(dir)
+		Expect(newer).NotTo(BeNil())
+
+		Expect(diff(older, newer)).To(Equal([]change{
+			{DirChangeRemoved, "a"},
+			{DirChangeAdded, "b"},
+			{DirChangeInode, "d"},
+			{DirChangeRemoved, "g"},
+			{DirChangeAdded, "h"},
+			{DirChangeAdded, "i"},
+			{DirChangeAdded, "x"},
+		}))
+		Expect(diff(newer, newer)).To(BeEmpty())
+		Expect(diff(nil, older)).To(HaveLen(older.Len()))
+		Expect(diff(older, nil)).To(HaveEach(HaveField("Change", DirChangeRemoved)))
+
+		count := 0
+		for range older.Diff(newer) {
+			count++
+			break
+		}
+		Expect(count).To(Equal(1))
+
+		Expect(testing.AllocsPerRun(10, func() {
+			for range older.Diff(newer) {
+			}
+		})).To(BeNumerically("<=", 1))
+	})
+
+})
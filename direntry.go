@@ -19,14 +19,18 @@ package faf
 import (
 	"encoding/binary"
 	"fmt"
+	"io/fs"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
 
+// DirEntryType is the type of a directory entry, as returned by the
+// getdents64 syscall.
 type DirEntryType uint8
 
 const (
+	DirEntryUnknown DirEntryType = unix.DT_UNKNOWN
 	DirEntryFIFO    DirEntryType = unix.DT_FIFO
 	DirEntryChar    DirEntryType = unix.DT_CHR
 	DirEntryBlock   DirEntryType = unix.DT_BLK
@@ -43,7 +47,30 @@ func (t DirEntryType) String() string {
 	return fmt.Sprintf("DirEntryType(%d)", t)
 }
 
+// FileMode returns the file type bits in form of a [fs.FileMode] for this
+// directory entry type. It returns [fs.ModeIrregular] for unknown types.
+func (t DirEntryType) FileMode() fs.FileMode {
+	switch t {
+	case DirEntryRegular:
+		return 0
+	case DirEntryDir:
+		return fs.ModeDir
+	case DirEntrySymlink:
+		return fs.ModeSymlink
+	case DirEntryFIFO:
+		return fs.ModeNamedPipe
+	case DirEntrySocket:
+		return fs.ModeSocket
+	case DirEntryChar:
+		return fs.ModeDevice | fs.ModeCharDevice
+	case DirEntryBlock:
+		return fs.ModeDevice
+	}
+	return fs.ModeIrregular
+}
+
 var dirEntryTypeDescription = map[DirEntryType]string{
+	DirEntryUnknown: "unknown",
 	DirEntryFIFO:    "FIFO/pipe",
 	DirEntryChar:    "char device",
 	DirEntryBlock:   "block device",
@@ -53,8 +80,8 @@ var dirEntryTypeDescription = map[DirEntryType]string{
 	DirEntrySocket:  "socket",
 }
 
-// DirEntry represents a single directory entry with only name, type and inode
-// number.
+// DirEntry represents a single directory entry with only name, type, inode
+// number, and offset cookie.
 //
 // Please note that in order to avoid heap escaping allocations, DirEntry
 // represents the name in the directory entry as a slice of bytes with its
@@ -69,8 +96,13 @@ var dirEntryTypeDescription = map[DirEntryType]string{
 // [compiler optimizations for conversions between strings and byte slices]: https://go101.org/article/string.html
 type DirEntry struct {
 	Ino  uint64
+	Off  int64 // opaque cookie of the next directory entry, see [ReadDirFrom].
 	Name []byte
 	Type DirEntryType
+
+	// file descriptor of the directory this entry was read from, plus one, so
+	// the zero value indicates that the directory is unknown.
+	dirfd int
 }
 
 // String returns a compact textual description of this DirEntry.
@@ -78,6 +110,11 @@ func (d DirEntry) String() string {
 	return fmt.Sprintf("DirEntry ino: %d, name: %q, type: %s", d.Ino, string(d.Name), d.Type)
 }
 
+// IsUnknown returns true if the type of this directory entry is unknown, as
+// the file system doesn't return types in directory entries. See also
+// [ResolveUnknownTypes].
+func (d DirEntry) IsUnknown() bool { return d.Type == unix.DT_UNKNOWN }
+
 // IsDir returns true if this directory entry is about a directory.
 func (d DirEntry) IsDir() bool { return d.Type == unix.DT_DIR }
 
@@ -99,6 +136,56 @@ func (d DirEntry) IsCharDev() bool { return d.Type == unix.DT_CHR }
 // IsBlockDev returns true if this directory entry is about a block device.
 func (d DirEntry) IsBlockDev() bool { return d.Type == unix.DT_BLK }
 
+// DirFd returns the file descriptor of the directory this directory entry has
+// been read from, or -1 if unknown. The directory file descriptor is only valid
+// inside the loop body iterating over the directory entries, and it must not
+// be closed. It can be used with [ReadFileAt] and [ReadDirAt] to access this
+// directory entry or things below it without having to build full paths.
+//
+// DirFd returns -1 for directory entries not stemming from directory
+// iteration, such as directory entries from a [DirSnapshot].
+func (d DirEntry) DirFd() int { return d.dirfd - 1 }
+
+// Stat stats this directory entry relative to the directory it has been read
+// from, without following symbolic links, returning true if successful. The
+// stat results are stored in the caller-supplied st, avoiding any heap
+// allocations, as well as the need to build the full path and the kernel
+// having to walk it.
+//
+// Stat must only be called on directory entries yielded by [ReadDir] and its
+// siblings, and only inside the loop body while the directory is still open.
+// Stat returns false for directory entries not stemming from directory
+// iteration, such as directory entries from a [DirSnapshot].
+//
+// Under the hood, Stat uses statx(2) for basic stats.
+func (d DirEntry) Stat(st *unix.Stat_t) bool {
+	var stx unix.Statx_t
+	if !d.Statx(unix.STATX_BASIC_STATS, &stx) {
+		return false
+	}
+	statxToStat(&stx, st)
+	return true
+}
+
+// Statx stats this directory entry relative to the directory it has been read
+// from, without following symbolic links, returning true if successful. The
+// mask specifies the fields the caller is interested in, such as
+// [unix.STATX_SIZE]|[unix.STATX_MTIME]; the kernel might return fewer or
+// more fields. The stat results are stored in the caller-supplied stx,
+// avoiding any heap allocations.
+//
+// The same restrictions as for [DirEntry.Stat] apply.
+//
+// See also [statx(2)] for background details.
+//
+// [statx(2)]: https://man7.org/linux/man-pages/man2/statx.2.html
+func (d DirEntry) Statx(mask int, stx *unix.Statx_t) bool {
+	if d.dirfd <= 0 {
+		return false
+	}
+	return statxBytes(d.dirfd-1, d.Name, unix.AT_SYMLINK_NOFOLLOW, mask, stx) == 0
+}
+
 // RawDirEntry64 provides convenient access to a directory entry within a byte
 // slice, as returned by the getdents64() syscall.
 //
@@ -113,6 +200,15 @@ func (d RawDirEntry64) Ino() (uint64, bool) {
 	return readUint(d, unsafe.Offsetof(unix.Dirent{}.Ino), unsafe.Sizeof(unix.Dirent{}.Ino))
 }
 
+// Off returns the opaque offset cookie of this directory entry and ok;
+// otherwise, it returns false. The offset cookie of a directory entry actually
+// references the next directory entry and can be used to resume reading a
+// directory after this entry, see also [ReadDirFrom].
+func (d RawDirEntry64) Off() (int64, bool) {
+	off, ok := readUint(d, unsafe.Offsetof(unix.Dirent{}.Off), unsafe.Sizeof(unix.Dirent{}.Off))
+	return int64(off), ok
+}
+
 // Len returns the length of this directory entry, or false.
 func (d RawDirEntry64) Len() (uint64, bool) {
 	return readUint(d, unsafe.Offsetof(unix.Dirent{}.Reclen), unsafe.Sizeof(unix.Dirent{}.Reclen))
@@ -19,6 +19,7 @@ package faf
 import (
 	"bytes"
 	"encoding/binary"
+	"io/fs"
 	"unsafe"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -90,6 +91,7 @@ var _ = Describe("directory entries", func() {
 		func(t DirEntryType, expected string) {
 			Expect(t.String()).To(ContainSubstring(expected))
 		},
+		Entry(nil, DirEntryUnknown, "unknown"),
 		Entry(nil, DirEntryFIFO, "pipe"),
 		Entry(nil, DirEntryChar, "char device"),
 		Entry(nil, DirEntryBlock, "block device"),
@@ -100,6 +102,20 @@ var _ = Describe("directory entries", func() {
 		Entry(nil, DirEntryType(42), "DirEntryType(42)"),
 	)
 
+	DescribeTable("DirEntryType file modes",
+		func(t DirEntryType, expected fs.FileMode) {
+			Expect(t.FileMode()).To(Equal(expected))
+		},
+		Entry(nil, DirEntryUnknown, fs.ModeIrregular),
+		Entry(nil, DirEntryFIFO, fs.ModeNamedPipe),
+		Entry(nil, DirEntryChar, fs.ModeDevice|fs.ModeCharDevice),
+		Entry(nil, DirEntryBlock, fs.ModeDevice),
+		Entry(nil, DirEntryDir, fs.ModeDir),
+		Entry(nil, DirEntryRegular, fs.FileMode(0)),
+		Entry(nil, DirEntrySymlink, fs.ModeSymlink),
+		Entry(nil, DirEntrySocket, fs.ModeSocket),
+	)
+
 	Context("cooked directory entries", func() {
 
 		It("returns a textual description", func() {
@@ -124,6 +140,11 @@ var _ = Describe("directory entries", func() {
 			Entry(nil, DirEntryBlock, DirEntry.IsBlockDev),
 		)
 
+		It("checks for unknown type", func() {
+			Expect(DirEntry{}.IsUnknown()).To(BeTrue())
+			Expect(DirEntry{Type: DirEntryDir}.IsUnknown()).To(BeFalse())
+		})
+
 	})
 
 	Context("raw directory entries", func() {
@@ -151,6 +172,13 @@ var _ = Describe("directory entries", func() {
 				Expect(name).To(BeEmpty())
 			})
 
+			It("rejects getting the offset", func() {
+				rde := makeRawDirEntry64(666, "foobarz", DirEntryDir)
+				off, ok := rde[:int(unsafe.Offsetof(unix.Dirent{}.Off))].Off()
+				Expect(ok).To(BeFalse())
+				Expect(off).To(BeZero())
+			})
+
 			It("rejects getting the type", func() {
 				rde := makeRawDirEntry64(666, "foobarz", DirEntryDir)
 				t, ok := rde[:int(unsafe.Offsetof(unix.Dirent{}.Type))].Type()
@@ -162,7 +190,9 @@ var _ = Describe("directory entries", func() {
 
 		It("returns correct properties", func() {
 			rde := makeRawDirEntry64(666, "foobarz", DirEntrySocket)
+			binary.NativeEndian.PutUint64(rde[unsafe.Offsetof(unix.Dirent{}.Off):], uint64(1<<62+42))
 			Expect(Ok(rde.Ino())).To(Equal(uint64(666)))
+			Expect(Ok(rde.Off())).To(Equal(int64(1<<62 + 42)))
 			Expect(Ok(rde.Type())).To(Equal(DirEntrySocket))
 			Expect(Ok(rde.Name())).To(Equal([]byte("foobarz")))
 
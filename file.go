@@ -0,0 +1,81 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// File is an open file for repeatedly re-reading its full contents into an
+// internal buffer, such as when monitoring /proc/$PID/stat, /proc/loadavg, or
+// a cgroup's cpu.stat. As procfs, sysfs, and cgroupfs regenerate the contents
+// on reading from offset 0, File returns fresh contents on each
+// [File.Read], while saving the open and close syscalls otherwise needed by
+// [ReadFile].
+//
+// The zero value of File is a closed File.
+type File struct {
+	fd     int // file descriptor plus one, so the zero value is closed.
+	buffer []byte
+}
+
+// OpenFile opens the named file for repeated re-reading, returning the File and
+// true. If the file cannot be opened, OpenFile returns nil and false.
+func OpenFile(name string) (*File, bool) {
+	return OpenFileAt(unix.AT_FDCWD, name)
+}
+
+// OpenFileAt works like [OpenFile], but a relative file name is relative to the
+// directory referenced by the dirfd file descriptor (instead of the current
+// working directory).
+func OpenFileAt(dirfd int, name string) (*File, bool) {
+	fd, errno := openat(dirfd, name, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if errno != 0 {
+		return nil, false
+	}
+	return &File{fd: fd + 1}, true
+}
+
+// Read re-reads the full contents of the file from offset 0 into the file's
+// internal buffer, growing it as necessary, returning the contents and true.
+// If the file cannot be read, Read returns false.
+//
+// Please note that the contents returned are only valid until the next call
+// to Read or [File.Close], as the internal buffer gets reused.
+func (f *File) Read() ([]byte, bool) {
+	if f.fd <= 0 {
+		return nil, false
+	}
+	var errno unix.Errno
+	f.buffer, _, errno = readFdAt(f.fd-1, f.buffer, 0, -1)
+	return f.buffer, errno == 0
+}
+
+// Fd returns the file descriptor of this File, or -1 if closed.
+func (f *File) Fd() int { return f.fd - 1 }
+
+// Close closes this File, rendering it unusable for reading. Closing an already
+// closed File is a no-op.
+func (f *File) Close() error {
+	if f.fd <= 0 {
+		return nil
+	}
+	err := unix.Close(f.fd - 1)
+	f.fd = 0
+	f.buffer = nil
+	return err
+}
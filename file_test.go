@@ -0,0 +1,103 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("re-readable files", func() {
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+	})
+
+	It("reports when given an unreadable file", func() {
+		f, ok := OpenFile("./_testdata/non-existing")
+		Expect(ok).To(BeFalse())
+		Expect(f).To(BeNil())
+
+		f = Ok(OpenFile("./_testdata"))
+		defer f.Close()
+		_, ok = f.Read()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("treats the zero value as closed", func() {
+		var f File
+		Expect(f.Fd()).To(Equal(-1))
+		_, ok := f.Read()
+		Expect(ok).To(BeFalse())
+		Expect(f.Close()).To(Succeed())
+	})
+
+	It("re-reads a file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "foo")
+		Expect(os.WriteFile(path, []byte("foo"), 0644)).To(Succeed())
+
+		f := Ok(OpenFile(path))
+		defer f.Close()
+		Expect(f.Fd()).NotTo(BeNumerically("<", 0))
+		Expect(Ok(f.Read())).To(Equal([]byte("foo")))
+		Expect(Ok(f.Read())).To(Equal([]byte("foo")))
+
+		Expect(os.WriteFile(path, []byte("barz"), 0644)).To(Succeed())
+		Expect(Ok(f.Read())).To(Equal([]byte("barz")))
+
+		Expect(f.Close()).To(Succeed())
+		Expect(f.Fd()).To(Equal(-1))
+		_, ok := f.Read()
+		Expect(ok).To(BeFalse())
+		Expect(f.Close()).To(Succeed())
+	})
+
+	It("re-reads a large file relative to a directory fd", func() {
+		dirfd := Successful(unix.Open(".", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0))
+		defer unix.Close(dirfd)
+		_, ok := OpenFileAt(dirfd, "non-existing")
+		Expect(ok).To(BeFalse())
+
+		osrContents := Successful(os.ReadFile("LICENSE"))
+		f := Ok(OpenFileAt(dirfd, "LICENSE"))
+		defer f.Close()
+		Expect(Ok(f.Read())).To(Equal(osrContents))
+		Expect(Ok(f.Read())).To(Equal(osrContents))
+	})
+
+	It("re-reads procfs files with fresh contents and without allocating", func() {
+		f := Ok(OpenFile("/proc/self/stat"))
+		defer f.Close()
+		Expect(Ok(f.Read())).To(MatchRegexp(`^\d+ \(`))
+		Expect(testing.AllocsPerRun(100, func() {
+			if _, ok := f.Read(); !ok {
+				panic("cannot re-read")
+			}
+		})).To(BeZero())
+	})
+
+})
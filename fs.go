@@ -0,0 +1,153 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// FS is a file system rooted at the specified directory, implementing
+// [fs.FS], [fs.ReadDirFS], [fs.ReadFileFS], and [fs.StatFS]. FS can be
+// dropped in wherever [os.DirFS] is used, such as in code taking an fs.FS for
+// testability, reading directories and files using [ReadDir] and [ReadFile]
+// under the hood.
+//
+//	fsys := faf.FS("/proc")
+//
+// Please note that in order to fulfill the fs.FS contracts, directory entry
+// names need to be converted into strings and file contents need to be read
+// into fresh buffers, so FS cannot avoid heap allocations as the direct use
+// of ReadDir and ReadFile can.
+type FS string
+
+var (
+	_ fs.FS         = FS("")
+	_ fs.ReadDirFS  = FS("")
+	_ fs.ReadFileFS = FS("")
+	_ fs.StatFS     = FS("")
+)
+
+// join returns the path of name inside this file system, or an error if name
+// isn't a valid path according to [fs.ValidPath].
+func (f FS) join(op string, name string) (string, error) {
+	if f == "" {
+		return "", &fs.PathError{Op: op, Path: name, Err: errors.New("FS with empty root")}
+	}
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return string(f), nil
+	}
+	return strings.TrimSuffix(string(f), "/") + "/" + name, nil
+}
+
+// Open opens the named file.
+func (f FS) Open(name string) (fs.File, error) {
+	fullname, err := f.join("open", name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fullname)
+	if err != nil {
+		// Don't leak the root of this file system into the error.
+		err.(*fs.PathError).Path = name
+		return nil, err
+	}
+	return file, nil
+}
+
+// ReadDir reads the named directory and returns a list of directory entries
+// sorted by filename.
+func (f FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fullname, err := f.join("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries := []fs.DirEntry{}
+	for dirEntry, err := range ReadDirE(fullname, ResolveUnknownTypes) {
+		if err != nil {
+			sortDirEntries(entries)
+			return entries, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		entries = append(entries, &fsDirEntry{
+			dir:  fullname,
+			name: string(dirEntry.Name),
+			typ:  dirEntry.Type.FileMode(),
+		})
+	}
+	sortDirEntries(entries)
+	return entries, nil
+}
+
+// sortDirEntries sorts the directory entries by name.
+func sortDirEntries(entries []fs.DirEntry) {
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+}
+
+// ReadFile reads the named file and returns its contents.
+func (f FS) ReadFile(name string) ([]byte, error) {
+	fullname, err := f.join("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	contents, errno := readFileAt(unix.AT_FDCWD, fullname, nil)
+	if errno != 0 {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: errno}
+	}
+	return contents, nil
+}
+
+// Stat returns a [fs.FileInfo] describing the named file.
+func (f FS) Stat(name string) (fs.FileInfo, error) {
+	fullname, err := f.join("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(fullname)
+	if err != nil {
+		err.(*fs.PathError).Path = name
+		return nil, err
+	}
+	return info, nil
+}
+
+// fsDirEntry implements [fs.DirEntry] for directory entries of an [FS].
+type fsDirEntry struct {
+	dir  string
+	name string
+	typ  fs.FileMode
+}
+
+func (d *fsDirEntry) Name() string      { return d.name }
+func (d *fsDirEntry) IsDir() bool       { return d.typ.IsDir() }
+func (d *fsDirEntry) Type() fs.FileMode { return d.typ }
+func (d *fsDirEntry) String() string    { return fs.FormatDirEntry(d) }
+
+// Info returns the [fs.FileInfo] for the directory entry, without following
+// symbolic links.
+func (d *fsDirEntry) Info() (fs.FileInfo, error) {
+	return os.Lstat(d.dir + "/" + d.name)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("fs.FS adapter", func() {
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+	})
+
+	It("passes fstest", func() {
+		Expect(fstest.TestFS(FS("_testdata"), "foo/bar", "foo/baz/.notempty")).To(Succeed())
+		Expect(fstest.TestFS(FS("_testdata/"), "foo/bar", "foo/baz/.notempty")).To(Succeed())
+	})
+
+	It("passes fstest with contents and symlinks", func() {
+		root := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(root, "a/b"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "a/b/c"), []byte("Hellorld!"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "d"), []byte("D'oh!"), 0644)).To(Succeed())
+		Expect(os.Symlink("a/b/c", filepath.Join(root, "l"))).To(Succeed())
+		fsys := FS(root)
+		Expect(fstest.TestFS(fsys, "a/b/c", "d", "l")).To(Succeed())
+
+		Expect(Successful(fs.ReadFile(fsys, "l"))).To(Equal([]byte("Hellorld!")))
+		entries := Successful(fs.ReadDir(fsys, "."))
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[2].Name()).To(Equal("l"))
+		Expect(entries[2].Type()).To(Equal(fs.ModeSymlink))
+		Expect(Successful(entries[2].Info()).Mode().Type()).To(Equal(fs.ModeSymlink))
+		Expect(entries[0].IsDir()).To(BeTrue())
+		Expect(entries[0].(fmt.Stringer).String()).To(Equal("d a/"))
+	})
+
+	It("reports errors", func() {
+		fsys := FS("_testdata")
+
+		_, err := fsys.Open("non-existing")
+		Expect(err).To(And(MatchError(fs.ErrNotExist), MatchError(ContainSubstring("open non-existing:"))))
+		_, err = fsys.ReadDir("non-existing")
+		Expect(err).To(And(MatchError(fs.ErrNotExist), MatchError(ContainSubstring("readdir non-existing:"))))
+		_, err = fsys.ReadFile("non-existing")
+		Expect(err).To(And(MatchError(fs.ErrNotExist), MatchError(ContainSubstring("readfile non-existing:"))))
+		_, err = fsys.Stat("non-existing")
+		Expect(err).To(And(MatchError(fs.ErrNotExist), MatchError(ContainSubstring("stat non-existing:"))))
+
+		_, err = fsys.Open("../foo")
+		Expect(err).To(MatchError(fs.ErrInvalid))
+		_, err = fsys.ReadDir("/foo")
+		Expect(err).To(MatchError(fs.ErrInvalid))
+		_, err = fsys.ReadFile("foo/")
+		Expect(err).To(MatchError(fs.ErrInvalid))
+		_, err = fsys.Stat("foo/../bar")
+		Expect(err).To(MatchError(fs.ErrInvalid))
+
+		_, err = FS("").Open(".")
+		Expect(err).To(HaveOccurred())
+	})
+
+})
@@ -0,0 +1,227 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"context"
+	"iter"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// InotifyEvent represents a single inotify event, where the Name field is
+// only set for events on entries inside watched directories.
+//
+// Please note that in order to avoid heap allocations, the Name field
+// references the underlying read buffer and thus is only valid inside the loop
+// body, similar to [DirEntry].
+//
+// See also [inotify(7)] for background details.
+//
+// [inotify(7)]: https://man7.org/linux/man-pages/man7/inotify.7.html
+type InotifyEvent struct {
+	Wd     int32  // watch descriptor
+	Path   string // watched path the event is about
+	Mask   uint32 // event mask, such as [unix.IN_CREATE]
+	Cookie uint32 // cookie associating related events, such as renames
+	Name   []byte // name of entry in watched directory, if any
+}
+
+// RawInotifyEvent provides convenient access to an inotify event within a byte
+// slice, as read from an inotify file descriptor.
+//
+// See also [inotify(7)] for background details.
+//
+// [inotify(7)]: https://man7.org/linux/man-pages/man7/inotify.7.html
+type RawInotifyEvent []byte
+
+// Wd returns the watch descriptor of this inotify event and ok; otherwise, it
+// returns false.
+func (e RawInotifyEvent) Wd() (int32, bool) {
+	wd, ok := readUint(e, unsafe.Offsetof(unix.InotifyEvent{}.Wd), unsafe.Sizeof(unix.InotifyEvent{}.Wd))
+	return int32(wd), ok
+}
+
+// Mask returns the event mask of this inotify event, or false.
+func (e RawInotifyEvent) Mask() (uint32, bool) {
+	mask, ok := readUint(e, unsafe.Offsetof(unix.InotifyEvent{}.Mask), unsafe.Sizeof(unix.InotifyEvent{}.Mask))
+	return uint32(mask), ok
+}
+
+// Cookie returns the cookie of this inotify event, or false.
+func (e RawInotifyEvent) Cookie() (uint32, bool) {
+	cookie, ok := readUint(e, unsafe.Offsetof(unix.InotifyEvent{}.Cookie), unsafe.Sizeof(unix.InotifyEvent{}.Cookie))
+	return uint32(cookie), ok
+}
+
+// Len returns the length of this inotify event, including the fixed-size
+// header and the NUL-padded name, or false.
+func (e RawInotifyEvent) Len() (uint64, bool) {
+	namelen, ok := readUint(e, unsafe.Offsetof(unix.InotifyEvent{}.Len), unsafe.Sizeof(unix.InotifyEvent{}.Len))
+	if !ok {
+		return 0, false
+	}
+	return unix.SizeofInotifyEvent + namelen, true
+}
+
+// Name returns the name of this inotify event, or false. The name is empty for
+// events about the watched path itself. The name returned references the
+// underlying inotify event and thus becomes invalid as soon as the underlying
+// inotify event gets overwritten.
+func (e RawInotifyEvent) Name() ([]byte, bool) {
+	eventLen, ok := e.Len()
+	if !ok || eventLen > uint64(len(e)) {
+		return nil, false
+	}
+	for pos := uint64(unix.SizeofInotifyEvent); pos < eventLen; pos++ {
+		if e[pos] == 0 {
+			return e[unix.SizeofInotifyEvent:pos], true
+		}
+	}
+	return e[unix.SizeofInotifyEvent:eventLen], true
+}
+
+// Watch returns an iterator over inotify events for the specified paths and
+// event mask, such as [unix.IN_CREATE]|[unix.IN_DELETE]. Paths that cannot be
+// watched are silently skipped; if none of the paths can be watched, the
+// iterator does not produce any events.
+//
+// The iterator blocks while waiting for new events and only returns when the
+// loop body stops the iteration, or reading events fails. Please note that the
+// loop body can only stop the iteration after an event has been yielded; use
+// [WatchContext] in order to be able to cancel waiting for events.
+//
+// Watch reads the events into a pooled read buffer, so watching doesn't
+// produce per-event heap garbage. The Name fields of the events yielded are
+// thus only valid inside the loop body.
+func Watch(paths []string, mask uint32) iter.Seq[InotifyEvent] {
+	return WatchContext(context.Background(), paths, mask)
+}
+
+// WatchContext works like [Watch], but additionally stops the iteration when
+// the specified context gets cancelled, even while waiting for events.
+func WatchContext(ctx context.Context, paths []string, mask uint32) iter.Seq[InotifyEvent] {
+	return func(yield func(InotifyEvent) bool) {
+		if ctx.Err() != nil {
+			return
+		}
+		fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+		if err != nil {
+			return
+		}
+		defer unix.Close(fd)
+		wds := make([]int32, len(paths))
+		watching := false
+		for idx, path := range paths {
+			wd, err := unix.InotifyAddWatch(fd, path, mask)
+			if err != nil {
+				wds[idx] = -1
+				continue
+			}
+			wds[idx] = int32(wd)
+			watching = true
+		}
+		if !watching {
+			return
+		}
+
+		// In case of a cancellable context, wait for either events or the
+		// context being done; the latter gets signalled using an eventfd.
+		var pollfds []unix.PollFd
+		if ctx.Done() != nil {
+			efd, err := unix.Eventfd(0, unix.EFD_CLOEXEC)
+			if err != nil {
+				return
+			}
+			defer unix.Close(efd)
+			signalled := make(chan struct{})
+			stop := context.AfterFunc(ctx, func() {
+				defer close(signalled)
+				var one [8]byte
+				*(*uint64)(unsafe.Pointer(&one[0])) = 1
+				_, _ = unix.Write(efd, one[:])
+			})
+			defer func() {
+				// make sure to not close the eventfd while it still might
+				// get signalled.
+				if !stop() {
+					<-signalled
+				}
+			}()
+			pollfds = []unix.PollFd{
+				{Fd: int32(fd), Events: unix.POLLIN},
+				{Fd: int32(efd), Events: unix.POLLIN},
+			}
+		}
+
+		rb := readDirBuffer.Get().(*readBuffer)
+		defer readDirBuffer.Put(rb)
+		buff := rb.buff
+		var event InotifyEvent
+		for {
+			if pollfds != nil {
+				_, err := unix.Poll(pollfds, -1)
+				if err == unix.EINTR {
+					continue
+				}
+				if err != nil || pollfds[1].Revents != 0 {
+					return
+				}
+			}
+			avail, err := unix.Read(fd, buff)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil || avail <= 0 {
+				return
+			}
+			for pos := 0; pos < avail; {
+				revent := RawInotifyEvent(buff[pos:avail])
+				eventLen, ok := revent.Len()
+				if !ok || eventLen > uint64(len(revent)) {
+					return
+				}
+				pos += int(eventLen)
+				event.Wd, _ = revent.Wd()
+				event.Mask, _ = revent.Mask()
+				event.Cookie, _ = revent.Cookie()
+				event.Name, _ = revent.Name()
+				event.Path = watchedPath(paths, wds, event.Wd)
+				if !yield(event) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// watchedPath returns the watched path for the specified watch descriptor, or
+// "" if there is none. Paths that couldn't be watched have a watch descriptor
+// of -1 in wds, but as the kernel uses -1 also for [unix.IN_Q_OVERFLOW] events,
+// watchedPath never matches negative watch descriptors.
+func watchedPath(paths []string, wds []int32, wd int32) string {
+	if wd < 0 {
+		return ""
+	}
+	for idx, pathWd := range wds {
+		if pathWd == wd {
+			return paths[idx]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	"golang.org/x/sys/unix"
+)
+
+func makeRawInotifyEvent(wd int32, mask uint32, cookie uint32, name string, namelen int) RawInotifyEvent {
+	b := binary.NativeEndian.AppendUint32(nil, uint32(wd))
+	b = binary.NativeEndian.AppendUint32(b, mask)
+	b = binary.NativeEndian.AppendUint32(b, cookie)
+	b = binary.NativeEndian.AppendUint32(b, uint32(namelen))
+	b = append(b, name...)
+	return RawInotifyEvent(append(b, make([]byte, namelen-len(name))...))
+}
+
+var _ = Describe("inotify", func() {
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+	})
+
+	Context("raw inotify events", func() {
+
+		It("returns correct properties", func() {
+			revent := makeRawInotifyEvent(42, unix.IN_CREATE, 666, "foobar", 16)
+			Expect(Ok(revent.Wd())).To(Equal(int32(42)))
+			Expect(Ok(revent.Mask())).To(Equal(uint32(unix.IN_CREATE)))
+			Expect(Ok(revent.Cookie())).To(Equal(uint32(666)))
+			Expect(Ok(revent.Len())).To(Equal(uint64(unix.SizeofInotifyEvent + 16)))
+			Expect(Ok(revent.Name())).To(Equal([]byte("foobar")))
+
+			revent = makeRawInotifyEvent(42, unix.IN_CREATE, 666, "", 0)
+			Expect(Ok(revent.Name())).To(BeEmpty())
+
+			revent = makeRawInotifyEvent(42, unix.IN_CREATE, 666, "foobarz!", 8)
+			Expect(Ok(revent.Name())).To(Equal([]byte("foobarz!")))
+		})
+
+		It("rejects truncated events", func() {
+			revent := makeRawInotifyEvent(42, unix.IN_CREATE, 666, "foobar", 16)
+			_, ok := revent[:unsafe.Offsetof(unix.InotifyEvent{}.Cookie)].Cookie()
+			Expect(ok).To(BeFalse())
+			_, ok = revent[:unsafe.Offsetof(unix.InotifyEvent{}.Len)].Len()
+			Expect(ok).To(BeFalse())
+			_, ok = revent[:unsafe.Offsetof(unix.InotifyEvent{}.Len)].Name()
+			Expect(ok).To(BeFalse())
+			_, ok = revent[:len(revent)-1].Name()
+			Expect(ok).To(BeFalse())
+			_, ok = RawInotifyEvent(nil).Wd()
+			Expect(ok).To(BeFalse())
+			_, ok = RawInotifyEvent(nil).Mask()
+			Expect(ok).To(BeFalse())
+		})
+
+	})
+
+	It("maps watch descriptors to paths", func() {
+		paths := []string{"/non-existing", "/foo", "/bar"}
+		wds := []int32{-1, 1, 2}
+		Expect(watchedPath(paths, wds, 1)).To(Equal("/foo"))
+		Expect(watchedPath(paths, wds, 2)).To(Equal("/bar"))
+		Expect(watchedPath(paths, wds, 3)).To(BeEmpty())
+		Expect(watchedPath(paths, wds, -1)).To(BeEmpty(), "IN_Q_OVERFLOW event mistaken for unwatched path")
+	})
+
+	It("doesn't watch non-existing paths", func() {
+		for range Watch([]string{"./_testdata/non-existing"}, unix.IN_CREATE) {
+			Fail("unexpected event")
+		}
+	})
+
+	It("watches", func(ctx SpecContext) {
+		dir := GinkgoT().TempDir()
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(100 * time.Millisecond)
+			Expect(os.WriteFile(filepath.Join(dir, "foo"), nil, 0644)).To(Succeed())
+		}()
+		var events []InotifyEvent
+		for event := range WatchContext(ctx, []string{"./_testdata/non-existing", dir}, unix.IN_CREATE) {
+			event.Name = append([]byte(nil), event.Name...)
+			events = append(events, event)
+			break
+		}
+		Expect(events).To(ConsistOf(And(
+			HaveField("Path", dir),
+			HaveField("Mask", uint32(unix.IN_CREATE)),
+			HaveField("Name", []byte("foo")),
+			HaveField("Wd", Not(BeNumerically("<", 0))),
+		)))
+	}, SpecTimeout(10*time.Second))
+
+	It("stops watching when cancelled", func(ctx SpecContext) {
+		wctx, cancel := context.WithCancel(ctx)
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			cancel()
+		}()
+		for range WatchContext(wctx, []string{GinkgoT().TempDir()}, unix.IN_CREATE) {
+			Fail("unexpected event")
+		}
+		Expect(wctx.Err()).To(MatchError(context.Canceled))
+
+		for range WatchContext(wctx, []string{GinkgoT().TempDir()}, unix.IN_CREATE) {
+			Fail("unexpected event")
+		}
+	}, SpecTimeout(10*time.Second))
+
+})
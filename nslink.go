@@ -0,0 +1,103 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// NamespaceType identifies the type of a Linux kernel namespace, using the
+// CLONE_NEWxxx constant for the particular namespace type as its value.
+type NamespaceType uint64
+
+const (
+	NamespaceCgroup NamespaceType = unix.CLONE_NEWCGROUP
+	NamespaceIPC    NamespaceType = unix.CLONE_NEWIPC
+	NamespaceMount  NamespaceType = unix.CLONE_NEWNS
+	NamespaceNet    NamespaceType = unix.CLONE_NEWNET
+	NamespacePID    NamespaceType = unix.CLONE_NEWPID
+	NamespaceTime   NamespaceType = unix.CLONE_NEWTIME
+	NamespaceUser   NamespaceType = unix.CLONE_NEWUSER
+	NamespaceUTS    NamespaceType = unix.CLONE_NEWUTS
+)
+
+// String returns the name of this namespace type as used in namespace link
+// targets, such as “net”.
+func (t NamespaceType) String() string {
+	switch t {
+	case NamespaceCgroup:
+		return "cgroup"
+	case NamespaceIPC:
+		return "ipc"
+	case NamespaceMount:
+		return "mnt"
+	case NamespaceNet:
+		return "net"
+	case NamespacePID:
+		return "pid"
+	case NamespaceTime:
+		return "time"
+	case NamespaceUser:
+		return "user"
+	case NamespaceUTS:
+		return "uts"
+	}
+	return fmt.Sprintf("NamespaceType(%#x)", uint64(t))
+}
+
+// ParseNamespaceType returns the namespace type for the specified kind token
+// of a namespace link target, such as “net”, and true. Otherwise, it returns
+// zero and false.
+func ParseNamespaceType(kind []byte) (NamespaceType, bool) {
+	switch string(kind) {
+	case "cgroup":
+		return NamespaceCgroup, true
+	case "ipc":
+		return NamespaceIPC, true
+	case "mnt":
+		return NamespaceMount, true
+	case "net":
+		return NamespaceNet, true
+	case "pid":
+		return NamespacePID, true
+	case "time":
+		return NamespaceTime, true
+	case "user":
+		return NamespaceUser, true
+	case "uts":
+		return NamespaceUTS, true
+	}
+	return 0, false
+}
+
+// ParseNamespaceLink parses the specified namespace link target, such as
+// “net:[4026531840]” as read from /proc/$PID/ns/net, returning the namespace
+// type, inode number, and true. Otherwise, it returns false.
+func ParseNamespaceLink(b []byte) (NamespaceType, uint64, bool) {
+	bs := NewBytestring(b) // go-es without heap alloc/escape.
+	kind, ino, _, ok := bs.LinkTarget()
+	if !ok || ino == 0 {
+		return 0, 0, false
+	}
+	typ, ok := ParseNamespaceType(kind)
+	if !ok {
+		return 0, 0, false
+	}
+	return typ, ino, true
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("namespace links", func() {
+
+	DescribeTable("namespace types",
+		func(typ NamespaceType, name string) {
+			Expect(typ.String()).To(Equal(name))
+			Expect(Ok(ParseNamespaceType([]byte(name)))).To(Equal(typ))
+		},
+		Entry(nil, NamespaceCgroup, "cgroup"),
+		Entry(nil, NamespaceIPC, "ipc"),
+		Entry(nil, NamespaceMount, "mnt"),
+		Entry(nil, NamespaceNet, "net"),
+		Entry(nil, NamespacePID, "pid"),
+		Entry(nil, NamespaceTime, "time"),
+		Entry(nil, NamespaceUser, "user"),
+		Entry(nil, NamespaceUTS, "uts"),
+	)
+
+	It("rejects unknown namespace types", func() {
+		Expect(NamespaceType(0x42).String()).To(Equal("NamespaceType(0x42)"))
+		typ, ok := ParseNamespaceType([]byte("foo"))
+		Expect(ok).To(BeFalse())
+		Expect(typ).To(BeZero())
+	})
+
+	It("parses namespace links", func() {
+		typ, ino, ok := ParseNamespaceLink([]byte("net:[4026531840]"))
+		Expect(ok).To(BeTrue())
+		Expect(typ).To(Equal(NamespaceNet))
+		Expect(ino).To(Equal(uint64(4026531840)))
+
+		for _, link := range []string{"socket:[42]", "anon_inode:[eventfd]", "net:[0]", "/dev/null"} {
+			typ, ino, ok = ParseNamespaceLink([]byte(link))
+			Expect(ok).To(BeFalse(), "link %q", link)
+			Expect(typ).To(BeZero())
+			Expect(ino).To(BeZero())
+		}
+	})
+
+	It("parses our own namespace links without allocating", func() {
+		var st unix.Stat_t
+		Expect(unix.Stat("/proc/self/ns/net", &st)).To(Succeed())
+		buff := make([]byte, 0, 128)
+		var typ NamespaceType
+		var ino uint64
+		Expect(testing.AllocsPerRun(100, func() {
+			buff, _ = Readlink("/proc/self/ns/net", buff)
+			typ, ino, _ = ParseNamespaceLink(buff)
+		})).To(BeZero())
+		Expect(typ).To(Equal(NamespaceNet))
+		Expect(ino).To(Equal(st.Ino))
+	})
+
+})
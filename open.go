@@ -0,0 +1,186 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"bytes"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpathBufferSize is the size of the on-stack buffer for turning path names
+// into NUL-terminated C strings. Paths in procfs and sysfs tend to be short,
+// so we don't need to burn a full [unix.PathMax] stack buffer on each and
+// every open.
+const cpathBufferSize = 256
+
+// cpathBuffer is an on-stack buffer for NUL-terminated C strings.
+type cpathBuffer [cpathBufferSize]byte
+
+// cpath returns a pointer to a NUL-terminated copy of the specified path name.
+// If the name fits into the specified buffer, cpath copies it into that
+// buffer, otherwise it falls back to [unix.BytePtrFromString] that allocates
+// on the heap. If the name contains a NUL, cpath returns [unix.EINVAL].
+//
+// This avoids the heap allocation on each and every call to [unix.Open] that
+// results from [unix.BytePtrFromString] always allocating a new NUL-terminated
+// copy of the path.
+func cpath[S ~string | ~[]byte](buff *cpathBuffer, name S) (*byte, unix.Errno) {
+	if len(name) >= len(buff) {
+		p, err := unix.BytePtrFromString(string(name))
+		if err != nil {
+			return nil, unix.EINVAL
+		}
+		return p, 0
+	}
+	for idx := 0; idx < len(name); idx++ {
+		if name[idx] == 0 {
+			return nil, unix.EINVAL
+		}
+	}
+	n := copy(buff[:], name)
+	buff[n] = 0
+	return &buff[0], 0
+}
+
+// cpathBytes returns a pointer to a NUL-terminated path name. If the
+// specified byte slice already is NUL-terminated, a pointer into it is
+// returned, without any copying. Otherwise, cpathBytes falls back to [cpath].
+func cpathBytes(buff *cpathBuffer, name []byte) (*byte, unix.Errno) {
+	if n := len(name); n > 0 && name[n-1] == 0 {
+		if bytes.IndexByte(name, 0) != n-1 {
+			return nil, unix.EINVAL
+		}
+		return &name[0], 0
+	}
+	return cpath(buff, name)
+}
+
+// openat opens the file with the specified name relative to the directory
+// file descriptor dirfd, returning the file descriptor of the opened file.
+// Use [unix.AT_FDCWD] to open names relative to the current working
+// directory. In contrast to [unix.Openat], openat does not allocate on the
+// heap, unless the name is very long.
+func openat(dirfd int, name string, flags int, mode uint32) (int, unix.Errno) {
+	var buff cpathBuffer
+	p, errno := cpath(&buff, name)
+	if errno != 0 {
+		return -1, errno
+	}
+	return openatPtr(dirfd, p, flags, mode)
+}
+
+// openatBytes opens the file with the specified name relative to the directory
+// file descriptor dirfd, returning the file descriptor of the opened file. If
+// the name is NUL-terminated it is used as is, otherwise a NUL-terminated copy
+// is made on the stack.
+func openatBytes(dirfd int, name []byte, flags int, mode uint32) (int, unix.Errno) {
+	var buff cpathBuffer
+	p, errno := cpathBytes(&buff, name)
+	if errno != 0 {
+		return -1, errno
+	}
+	return openatPtr(dirfd, p, flags, mode)
+}
+
+// openatPtr issues the raw openat syscall with the specified NUL-terminated
+// path.
+func openatPtr(dirfd int, p *byte, flags int, mode uint32) (int, unix.Errno) {
+	fd, _, errno := unix.Syscall6(unix.SYS_OPENAT,
+		uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags), uintptr(mode),
+		0, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), 0
+}
+
+// readlinkat issues the raw readlinkat syscall for the specified name relative
+// to the directory file descriptor dirfd, reading the link target into the
+// specified buffer. It returns the number of bytes placed into the buffer.
+func readlinkat(dirfd int, name string, buff []byte) (int, unix.Errno) {
+	var cbuff cpathBuffer
+	p, errno := cpath(&cbuff, name)
+	if errno != 0 {
+		return 0, errno
+	}
+	var bp unsafe.Pointer
+	if len(buff) > 0 {
+		bp = unsafe.Pointer(&buff[0])
+	}
+	n, _, errno := unix.Syscall6(unix.SYS_READLINKAT,
+		uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(bp), uintptr(len(buff)),
+		0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), 0
+}
+
+// statxBytes issues the raw statx syscall for the specified name relative to
+// the directory file descriptor dirfd, without allocating on the heap (unless
+// the name is very long).
+func statxBytes(dirfd int, name []byte, flags int, mask int, stx *unix.Statx_t) unix.Errno {
+	var buff cpathBuffer
+	p, errno := cpathBytes(&buff, name)
+	if errno != 0 {
+		return errno
+	}
+	_, _, errno = unix.Syscall6(unix.SYS_STATX,
+		uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags), uintptr(mask),
+		uintptr(unsafe.Pointer(stx)), 0)
+	return errno
+}
+
+// errnoOf returns the errno of the specified error as returned by a syscall
+// wrapper from the unix package. Non-errno errors map to [unix.EIO].
+func errnoOf(err error) unix.Errno {
+	if errno, ok := err.(unix.Errno); ok {
+		return errno
+	}
+	return unix.EIO
+}
+
+// statxToStat fills the specified [unix.Stat_t] from the basic stats in the
+// specified [unix.Statx_t]. As the field types of unix.Stat_t differ between
+// architectures, the individual fields get set using the generic setStat.
+func statxToStat(stx *unix.Statx_t, st *unix.Stat_t) {
+	*st = unix.Stat_t{}
+	setStat(&st.Dev, unix.Mkdev(stx.Dev_major, stx.Dev_minor))
+	setStat(&st.Ino, stx.Ino)
+	setStat(&st.Nlink, uint64(stx.Nlink))
+	setStat(&st.Mode, uint64(stx.Mode))
+	setStat(&st.Uid, uint64(stx.Uid))
+	setStat(&st.Gid, uint64(stx.Gid))
+	setStat(&st.Rdev, unix.Mkdev(stx.Rdev_major, stx.Rdev_minor))
+	setStat(&st.Size, stx.Size)
+	setStat(&st.Blksize, uint64(stx.Blksize))
+	setStat(&st.Blocks, stx.Blocks)
+	setStat(&st.Atim.Sec, uint64(stx.Atime.Sec))
+	setStat(&st.Atim.Nsec, uint64(stx.Atime.Nsec))
+	setStat(&st.Mtim.Sec, uint64(stx.Mtime.Sec))
+	setStat(&st.Mtim.Nsec, uint64(stx.Mtime.Nsec))
+	setStat(&st.Ctim.Sec, uint64(stx.Ctime.Sec))
+	setStat(&st.Ctim.Nsec, uint64(stx.Ctime.Nsec))
+}
+
+// setStat sets a [unix.Stat_t] field of whatever integer type to the specified
+// value, truncating it as necessary.
+func setStat[T ~int32 | ~int64 | ~uint32 | ~uint64](field *T, val uint64) {
+	*field = T(val)
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("opening files", func() {
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+	})
+
+	Context("C paths", func() {
+
+		It("copies short paths into the buffer", func() {
+			var buff cpathBuffer
+			p, errno := cpath(&buff, "/proc")
+			Expect(errno).To(BeZero())
+			Expect(p).To(BeIdenticalTo(&buff[0]))
+			Expect(buff[:6]).To(Equal([]byte("/proc\x00")))
+		})
+
+		It("allocates long paths", func() {
+			var buff cpathBuffer
+			p, errno := cpath(&buff, strings.Repeat("x", cpathBufferSize))
+			Expect(errno).To(BeZero())
+			Expect(p).NotTo(BeIdenticalTo(&buff[0]))
+			Expect(unix.BytePtrToString(p)).To(HaveLen(cpathBufferSize))
+		})
+
+		It("rejects paths with NULs", func() {
+			var buff cpathBuffer
+			p, errno := cpath(&buff, "/pr\x00oc")
+			Expect(errno).To(Equal(unix.EINVAL))
+			Expect(p).To(BeNil())
+
+			p, errno = cpath(&buff, strings.Repeat("\x00", cpathBufferSize))
+			Expect(errno).To(Equal(unix.EINVAL))
+			Expect(p).To(BeNil())
+
+			p, errno = cpathBytes(&buff, []byte("/pr\x00oc\x00"))
+			Expect(errno).To(Equal(unix.EINVAL))
+			Expect(p).To(BeNil())
+		})
+
+		It("uses NUL-terminated byte slices as is", func() {
+			var buff cpathBuffer
+			name := []byte("/proc\x00")
+			p, errno := cpathBytes(&buff, name)
+			Expect(errno).To(BeZero())
+			Expect(p).To(BeIdenticalTo(&name[0]))
+
+			p, errno = cpathBytes(&buff, name[:5])
+			Expect(errno).To(BeZero())
+			Expect(p).To(BeIdenticalTo(&buff[0]))
+		})
+
+	})
+
+	It("opens and reports errors", func() {
+		fd, errno := openat(unix.AT_FDCWD, "_testdata/foo/bar", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		Expect(errno).To(BeZero())
+		Expect(fd).NotTo(BeNumerically("<", 0))
+		unix.Close(fd)
+
+		fd, errno = openatBytes(unix.AT_FDCWD, []byte("_testdata/foo/bar"), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		Expect(errno).To(BeZero())
+		Expect(fd).NotTo(BeNumerically("<", 0))
+		unix.Close(fd)
+
+		fd, errno = openat(unix.AT_FDCWD, "_testdata/non-existing", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		Expect(errno).To(Equal(unix.ENOENT))
+		Expect(fd).To(Equal(-1))
+
+		fd, errno = openat(unix.AT_FDCWD, "_testdata/\x00", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		Expect(errno).To(Equal(unix.EINVAL))
+		Expect(fd).To(Equal(-1))
+
+		fd, errno = openatBytes(unix.AT_FDCWD, []byte("_testdata/\x00\x00"), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		Expect(errno).To(Equal(unix.EINVAL))
+		Expect(fd).To(Equal(-1))
+	})
+
+})
@@ -40,6 +40,23 @@ func ParseUint(b []byte) (uint64, bool) {
 	return val, ok
 }
 
+// ParseInt parses the given byte slice with a decimal number with an optional
+// leading “-” sign, returning its int64 value and ok, or a zero value and false
+// in case of error. It is an error for the given decimal number to be outside
+// the int64 range or if there are bytes for characters other than "0" to "9"
+// (except for the leading sign).
+func ParseInt(b []byte) (int64, bool) {
+	buff := NewBytestring(b) // go-es without heap alloc/escape.
+	val, ok := buff.Int64()
+	if !ok {
+		return 0, ok
+	}
+	if !buff.EOL() {
+		return 0, false
+	}
+	return val, ok
+}
+
 // ParseHexUint parses the given byte slice with a hexadecimal number, returning
 // its uint64 value and ok, or a zero value and false in case of error. It is an
 // error for the given decimal number overflows the uint64 range or if there
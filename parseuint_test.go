@@ -53,6 +53,23 @@ var _ = Describe("parsing uint64s", func() {
 
 	})
 
+	Context("signed decimal", func() {
+
+		It("returns a correct value", func() {
+			Expect(Ok(ParseInt([]byte("42")))).To(Equal(int64(42)))
+			Expect(Ok(ParseInt([]byte("-1000")))).To(Equal(int64(-1000)))
+		})
+
+		It("rejects invalid numbers and trailing junk", func() {
+			for _, s := range []string{"", "-", "--1", "42DO'H!", "9223372036854775808"} {
+				v, ok := ParseInt([]byte(s))
+				Expect(ok).NotTo(BeTrue(), "%q", s)
+				Expect(v).To(BeZero())
+			}
+		})
+
+	})
+
 	Context("hexadecimal", func() {
 
 		It("returns a correct value", func() {
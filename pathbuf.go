@@ -0,0 +1,134 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// PathBuf builds file system paths in a fixed-size buffer without any heap
+// allocations, such as when building procfs paths in hot paths. A PathBuf is
+// small enough to be placed on the stack; its zero value is an empty path ready
+// to use.
+//
+//	var pb faf.PathBuf
+//	contents, ok := faf.ReadFile(pb.Reset("/proc/").Uint(pid).Str("/stat").String(), buff)
+//
+// The path in a PathBuf is always kept NUL-terminated, so it can be directly
+// handed to syscalls. If a path would grow beyond [unix.PathMax] bytes
+// (including the terminating NUL), the PathBuf becomes invalid and yields an
+// empty path until it gets [PathBuf.Reset].
+type PathBuf struct {
+	len      int
+	overflow bool
+	buff     [unix.PathMax]byte
+}
+
+// Reset the path buffer to the specified string, returning the path buffer
+// for chaining further operations.
+func (p *PathBuf) Reset(s string) *PathBuf {
+	p.len = 0
+	p.overflow = false
+	return p.Str(s)
+}
+
+// Str appends the specified string to the path, returning the path buffer.
+func (p *PathBuf) Str(s string) *PathBuf {
+	if p.overflow || p.len+len(s) >= len(p.buff) {
+		p.overflow = true
+		return p
+	}
+	p.len += copy(p.buff[p.len:], s)
+	p.buff[p.len] = 0
+	return p
+}
+
+// Append appends the specified byte slice to the path, returning the path
+// buffer. This is especially useful with the Name field of a [DirEntry].
+func (p *PathBuf) Append(b []byte) *PathBuf {
+	if p.overflow || p.len+len(b) >= len(p.buff) {
+		p.overflow = true
+		return p
+	}
+	p.len += copy(p.buff[p.len:], b)
+	p.buff[p.len] = 0
+	return p
+}
+
+// Uint appends the decimal representation of the specified unsigned number to
+// the path, returning the path buffer.
+func (p *PathBuf) Uint(num uint64) *PathBuf {
+	var digits [20]byte
+	return p.Append(AppendUint(digits[:0], num))
+}
+
+// HexUint appends the hexadecimal representation of the specified unsigned
+// number to the path, returning the path buffer.
+func (p *PathBuf) HexUint(num uint64) *PathBuf {
+	var digits [16]byte
+	return p.Append(AppendHexUint(digits[:0], num))
+}
+
+// Truncate shortens the path to the specified length, returning the path
+// buffer. Truncating to a length beyond the current length is a no-op.
+// Truncating an overflowed path makes it valid again, as failed appends never
+// change the path buffer contents.
+func (p *PathBuf) Truncate(n int) *PathBuf {
+	if n < 0 || n > p.len {
+		return p
+	}
+	p.len = n
+	p.overflow = false
+	p.buff[n] = 0
+	return p
+}
+
+// Len returns the length of the path, not including the terminating NUL.
+func (p *PathBuf) Len() int { return p.len }
+
+// Ok returns true if the path is valid, and false if the path overflowed the
+// buffer.
+func (p *PathBuf) Ok() bool { return !p.overflow }
+
+// Bytes returns the path as a byte slice, not including the terminating NUL.
+// In case of an overflowed path, Bytes returns nil.
+//
+// Please note that the returned byte slice references the path buffer and
+// thus gets invalidated with the next change to the path buffer.
+func (p *PathBuf) Bytes() []byte {
+	if p.overflow {
+		return nil
+	}
+	return p.buff[:p.len:p.len]
+}
+
+// String returns the path as a string without copying, so this is a
+// non-allocating operation. In case of an overflowed path, String returns an
+// empty string.
+//
+// Please note that the returned string directly references the path buffer,
+// so it must not be used anymore after the path buffer has been changed or
+// has gone out of scope. The string can be safely passed to faf's file and
+// directory functions, such as [ReadFile] and [ReadDir].
+func (p *PathBuf) String() string {
+	if p.overflow || p.len == 0 {
+		return ""
+	}
+	return unsafe.String(&p.buff[0], p.len)
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("path buffers", func() {
+
+	It("builds paths", func() {
+		var pb PathBuf
+		Expect(pb.String()).To(BeEmpty())
+		Expect(pb.Ok()).To(BeTrue())
+
+		pb.Reset("/proc/").Uint(42).Str("/task/").Uint(666).Str("/stat")
+		Expect(pb.Ok()).To(BeTrue())
+		Expect(pb.String()).To(Equal("/proc/42/task/666/stat"))
+		Expect(pb.Bytes()).To(Equal([]byte("/proc/42/task/666/stat")))
+		Expect(pb.Len()).To(Equal(len("/proc/42/task/666/stat")))
+		Expect(pb.buff[pb.Len()]).To(BeZero())
+
+		pb.Reset("/sys/").HexUint(0xbeef).Str("/").Append([]byte("foo"))
+		Expect(pb.String()).To(Equal("/sys/beef/foo"))
+		Expect(pb.buff[pb.Len()]).To(BeZero())
+	})
+
+	It("truncates paths", func() {
+		var pb PathBuf
+		pb.Reset("/proc/42/stat")
+		Expect(pb.Truncate(100).String()).To(Equal("/proc/42/stat"))
+		Expect(pb.Truncate(-1).String()).To(Equal("/proc/42/stat"))
+		Expect(pb.Truncate(8).String()).To(Equal("/proc/42"))
+		Expect(pb.buff[pb.Len()]).To(BeZero())
+		Expect(pb.Str("/status").String()).To(Equal("/proc/42/status"))
+	})
+
+	It("invalidates overflowing paths", func() {
+		var pb PathBuf
+		pb.Reset("/").Str(strings.Repeat("x", unix.PathMax-2))
+		Expect(pb.Ok()).To(BeTrue())
+		Expect(pb.Len()).To(Equal(unix.PathMax - 1))
+		Expect(pb.Str("y").Ok()).To(BeFalse())
+		Expect(pb.String()).To(BeEmpty())
+		Expect(pb.Bytes()).To(BeNil())
+		Expect(pb.Append([]byte("z")).Uint(42).Ok()).To(BeFalse())
+		Expect(pb.Truncate(2).Ok()).To(BeTrue())
+		Expect(pb.String()).To(Equal("/x"))
+		Expect(pb.Reset("/proc").Ok()).To(BeTrue())
+		Expect(pb.String()).To(Equal("/proc"))
+	})
+
+	It("is passable to ReadFile without allocating", func() {
+		osrContents := Successful(os.ReadFile("_testdata/foo/bar"))
+		buff := make([]byte, 0, 1024)
+		Expect(testing.AllocsPerRun(100, func() {
+			var pb PathBuf
+			var ok bool
+			buff, ok = ReadFile(pb.Reset("_testdata/").Str("foo").Str("/bar").String(), buff)
+			if !ok {
+				panic("cannot read file")
+			}
+		})).To(BeZero())
+		Expect(buff).To(Equal(osrContents))
+	})
+
+})
@@ -0,0 +1,219 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package faf
+
+import (
+	"bytes"
+	"path"
+	"unicode/utf8"
+)
+
+// Pattern is a shell file name pattern, as used by [path.Match], that has been
+// checked to be well-formed, so it can be matched against names in form of
+// byte slices, such as the Name field of [DirEntry].
+type Pattern string
+
+// CompilePattern checks the specified shell file name pattern to be
+// well-formed, returning it as a [Pattern]. Otherwise, it returns
+// [path.ErrBadPattern]. See [path.Match] for the pattern syntax.
+func CompilePattern(pattern string) (Pattern, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", err
+	}
+	return Pattern(pattern), nil
+}
+
+// MustCompilePattern is like [CompilePattern] but panics if the pattern is
+// malformed.
+func MustCompilePattern(pattern string) Pattern {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		panic("faf: malformed pattern " + pattern + ": " + err.Error())
+	}
+	return p
+}
+
+// Match reports whether the specified name matches the shell pattern, with the
+// same semantics as [path.Match]. In contrast to path.Match, Match works on a
+// byte slice instead of a string, so there is no need to convert names to
+// strings first, and thus no need for heap allocations.
+//
+// The matching algorithm has been adapted from Go's path.Match; Copyright 2010
+// The Go Authors; BSD-style license.
+func (p Pattern) Match(name []byte) bool {
+	pattern := string(p)
+Pattern:
+	for len(pattern) > 0 {
+		var star bool
+		var chunk string
+		star, chunk, pattern = scanChunk(pattern)
+		if star && chunk == "" {
+			// Trailing * matches rest of name unless it has a /.
+			return bytes.IndexByte(name, '/') < 0
+		}
+		// Look for match at current position.
+		rest, ok := matchChunk(chunk, name)
+		// if we're the last chunk, make sure we've exhausted the name
+		// otherwise we'll give a false result even if we could still match
+		// using the star.
+		if ok && (len(rest) == 0 || len(pattern) > 0) {
+			name = rest
+			continue
+		}
+		if star {
+			// Look for match skipping i+1 bytes; cannot skip /.
+			for i := 0; i < len(name) && name[i] != '/'; i++ {
+				rest, ok := matchChunk(chunk, name[i+1:])
+				if ok {
+					// if we're the last chunk, make sure we exhausted the name.
+					if len(pattern) == 0 && len(rest) > 0 {
+						continue
+					}
+					name = rest
+					continue Pattern
+				}
+			}
+		}
+		return false
+	}
+	return len(name) == 0
+}
+
+// scanChunk gets the next segment of pattern, which is a non-star string
+// possibly preceded by a star.
+func scanChunk(pattern string) (star bool, chunk, rest string) {
+	for len(pattern) > 0 && pattern[0] == '*' {
+		pattern = pattern[1:]
+		star = true
+	}
+	inrange := false
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+		case '[':
+			inrange = true
+		case ']':
+			inrange = false
+		case '*':
+			if !inrange {
+				return star, pattern[:i], pattern[i:]
+			}
+		}
+	}
+	return star, pattern, ""
+}
+
+// matchChunk checks whether chunk matches the beginning of s. If so, it
+// returns the remainder of s (after the match) and true. Chunk consists of
+// single-character operators only: literals, char classes, and “?”. Malformed
+// chunks never match.
+func matchChunk(chunk string, s []byte) (rest []byte, ok bool) {
+	// failed records whether the match has failed. After the match fails, the
+	// loop continues on processing chunk, but no longer reading s.
+	failed := false
+	for len(chunk) > 0 {
+		failed = failed || len(s) == 0
+		switch chunk[0] {
+		case '[':
+			// character class
+			var r rune
+			if !failed {
+				var n int
+				r, n = utf8.DecodeRune(s)
+				s = s[n:]
+			}
+			chunk = chunk[1:]
+			// possibly negated
+			negated := false
+			if len(chunk) > 0 && chunk[0] == '^' {
+				negated = true
+				chunk = chunk[1:]
+			}
+			// parse all ranges
+			match := false
+			nrange := 0
+			for {
+				if len(chunk) > 0 && chunk[0] == ']' && nrange > 0 {
+					chunk = chunk[1:]
+					break
+				}
+				var lo, hi rune
+				if lo, chunk, ok = getEsc(chunk); !ok {
+					return nil, false
+				}
+				hi = lo
+				if chunk[0] == '-' {
+					if hi, chunk, ok = getEsc(chunk[1:]); !ok {
+						return nil, false
+					}
+				}
+				match = match || lo <= r && r <= hi
+				nrange++
+			}
+			failed = failed || match == negated
+
+		case '?':
+			if !failed {
+				failed = s[0] == '/'
+				_, n := utf8.DecodeRune(s)
+				s = s[n:]
+			}
+			chunk = chunk[1:]
+
+		case '\\':
+			chunk = chunk[1:]
+			if len(chunk) == 0 {
+				return nil, false
+			}
+			fallthrough
+
+		default:
+			if !failed {
+				failed = chunk[0] != s[0]
+				s = s[1:]
+			}
+			chunk = chunk[1:]
+		}
+	}
+	if failed {
+		return nil, false
+	}
+	return s, true
+}
+
+// getEsc gets a possibly-escaped character from chunk, for a character class.
+func getEsc(chunk string) (r rune, nchunk string, ok bool) {
+	if len(chunk) == 0 || chunk[0] == '-' || chunk[0] == ']' {
+		return 0, "", false
+	}
+	if chunk[0] == '\\' {
+		chunk = chunk[1:]
+		if len(chunk) == 0 {
+			return 0, "", false
+		}
+	}
+	r, n := utf8.DecodeRuneInString(chunk)
+	if r == utf8.RuneError && n == 1 {
+		return 0, "", false
+	}
+	nchunk = chunk[n:]
+	if len(nchunk) == 0 {
+		return 0, "", false
+	}
+	return r, nchunk, true
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package faf
+
+import (
+	"path"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("shell file name patterns", func() {
+
+	It("rejects malformed patterns", func() {
+		for _, pattern := range []string{"[", "[^", "[-]", "a[]", "[x-]", "\\", "*\\", "[a-b-c]"} {
+			_, err := CompilePattern(pattern)
+			Expect(err).To(MatchError(path.ErrBadPattern), "pattern %q", pattern)
+		}
+		Expect(func() { MustCompilePattern("[") }).To(PanicWith(ContainSubstring("malformed pattern")))
+		Expect(MustCompilePattern("cpu[0-9]*")).To(Equal(Pattern("cpu[0-9]*")))
+	})
+
+	DescribeTable("matching like path.Match",
+		func(pattern, name string) {
+			expected, err := path.Match(pattern, name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(MustCompilePattern(pattern).Match([]byte(name))).To(Equal(expected))
+		},
+		Entry(nil, "abc", "abc"),
+		Entry(nil, "*", "abc"),
+		Entry(nil, "*c", "abc"),
+		Entry(nil, "a*", "a"),
+		Entry(nil, "a*", "abc"),
+		Entry(nil, "a*", "ab/c"),
+		Entry(nil, "a*/b", "abc/b"),
+		Entry(nil, "a*/b", "a/c/b"),
+		Entry(nil, "a*b*c*d*e*/f", "axbxcxdxe/f"),
+		Entry(nil, "a*b*c*d*e*/f", "axbxcxdxexxx/f"),
+		Entry(nil, "a*b*c*d*e*/f", "axbxcxdxe/xxx/f"),
+		Entry(nil, "a*b*c*d*e*/f", "axbxcxdxexxx/fff"),
+		Entry(nil, "a*b?c*x", "abxbbxdbxebxczzx"),
+		Entry(nil, "a*b?c*x", "abxbbxdbxebxczzy"),
+		Entry(nil, "ab[c]", "abc"),
+		Entry(nil, "ab[b-d]", "abc"),
+		Entry(nil, "ab[e-g]", "abc"),
+		Entry(nil, "ab[^c]", "abc"),
+		Entry(nil, "ab[^b-d]", "abc"),
+		Entry(nil, "ab[^e-g]", "abc"),
+		Entry(nil, "a\\*b", "a*b"),
+		Entry(nil, "a\\*b", "ab"),
+		Entry(nil, "a?b", "a☺b"),
+		Entry(nil, "a[^a]b", "a☺b"),
+		Entry(nil, "a???b", "a☺b"),
+		Entry(nil, "a[^a][^a][^a]b", "a☺b"),
+		Entry(nil, "[a-ζ]*", "α"),
+		Entry(nil, "*[a-ζ]", "A"),
+		Entry(nil, "a?b", "a/b"),
+		Entry(nil, "a*b", "a/b"),
+		Entry(nil, "[\\]a]", "]"),
+		Entry(nil, "[\\-]", "-"),
+		Entry(nil, "[x\\-]", "x"),
+		Entry(nil, "[x\\-]", "-"),
+		Entry(nil, "[x\\-]", "z"),
+		Entry(nil, "[\\-x]", "x"),
+		Entry(nil, "[\\-x]", "-"),
+		Entry(nil, "[\\-x]", "a"),
+		Entry(nil, "*x", "xxx"),
+		Entry(nil, "", ""),
+		Entry(nil, "", "a"),
+		Entry(nil, "cgroup.*", "cgroup.procs"),
+		Entry(nil, "cgroup.*", "cpu.max"),
+		Entry(nil, "cpu[0-9]*", "cpu42"),
+		Entry(nil, "cpu[0-9]*", "cpufreq"),
+		Entry(nil, "*.conf", "foo.conf"),
+		Entry(nil, "*.conf", "foo.conf.bak"),
+	)
+
+	It("doesn't allocate", func() {
+		p := MustCompilePattern("cpu[0-9]*")
+		name := []byte("cpu42")
+		Expect(testing.AllocsPerRun(100, func() {
+			if !p.Match(name) {
+				panic("no match")
+			}
+		})).To(BeZero())
+	})
+
+})
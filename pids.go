@@ -0,0 +1,73 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"iter"
+
+	"golang.org/x/sys/unix"
+)
+
+// PIDs returns an iterator over the PIDs of the processes found in the procfs
+// mounted at procRoot, which usually is “/proc”. PIDs only yields directory
+// entries with purely numerical names, parsing them directly inside the
+// directory reading loop. In case procRoot cannot be read, the iterator does
+// not produce any PIDs.
+//
+// PIDs has the same allocation behavior as [ReadDir], thus zero allocations
+// per PID.
+func PIDs(procRoot string) iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		fd, errno := openat(unix.AT_FDCWD, procRoot, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		readNumericDirFd(fd, yield)
+	}
+}
+
+// Tasks returns an iterator over the task IDs (TIDs) of the process with the
+// specified PID, as found in the procfs mounted at procRoot, which usually is
+// “/proc”. In case the process doesn't exist (anymore), the iterator does not
+// produce any TIDs.
+func Tasks(procRoot string, pid uint64) iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		var path PathBuf
+		fd, errno := openat(unix.AT_FDCWD,
+			path.Reset(procRoot).Str("/").Uint(pid).Str("/task").String(),
+			unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		readNumericDirFd(fd, yield)
+	}
+}
+
+// readNumericDirFd reads the directory entries from the already opened
+// directory file descriptor, yielding only the numbers of those directory
+// entries with decimal numbers as their names.
+func readNumericDirFd(fd int, yield func(uint64) bool) {
+	_ = readDirFd(fd, 0, func(dirEntry DirEntry) bool {
+		num, ok := ParseUint(dirEntry.Name)
+		if !ok {
+			return true
+		}
+		return yield(num)
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+)
+
+var _ = Describe("PIDs and TIDs", func() {
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+	})
+
+	It("enumerates only numerical directory entries", func() {
+		procRoot := GinkgoT().TempDir()
+		for _, path := range []string{"1/task/1", "42/task/42", "42/task/666", "self", "1a", "sys"} {
+			Expect(os.MkdirAll(filepath.Join(procRoot, path), 0755)).To(Succeed())
+		}
+		Expect(slices.Collect(PIDs(procRoot))).To(ConsistOf(uint64(1), uint64(42)))
+		Expect(slices.Collect(Tasks(procRoot, 42))).To(ConsistOf(uint64(42), uint64(666)))
+		Expect(slices.Collect(Tasks(procRoot, 666))).To(BeEmpty())
+		Expect(slices.Collect(PIDs(filepath.Join(procRoot, "non-existing")))).To(BeEmpty())
+	})
+
+	It("stops early", func() {
+		count := 0
+		for range PIDs("/proc") {
+			count++
+			break
+		}
+		Expect(count).To(Equal(1))
+	})
+
+	It("finds ourselves in procfs", func() {
+		pid := uint64(os.Getpid())
+		Expect(slices.Collect(PIDs("/proc"))).To(ContainElement(pid))
+		Expect(slices.Collect(Tasks("/proc", pid))).To(ContainElement(pid))
+	})
+
+	It("doesn't allocate", func() {
+		pid := uint64(os.Getpid())
+		Expect(testing.AllocsPerRun(10, func() {
+			for range PIDs("/proc") {
+			}
+		})).To(BeNumerically("<=", poolMissAllocs(1)))
+		Expect(testing.AllocsPerRun(10, func() {
+			for range Tasks("/proc", pid) {
+			}
+		})).To(BeNumerically("<=", poolMissAllocs(1)))
+	})
+
+})
@@ -0,0 +1,27 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build race
+
+package faf
+
+// raceEnabled tells whether the tests have been built with the race detector,
+// which changes sync.Pool and escape behavior.
+const raceEnabled = true
+
+// poolMissAllocs returns the number of allocations per run to tolerate in
+// allocation tests of code that gets buffers from sync.Pools: in race
+// builds, sync.Pool randomly drops items, so that getting a buffer then falls
+// back to allocating a new one, costing two allocations each time.
+func poolMissAllocs(gets int) float64 { return float64(2 * gets) }
@@ -17,9 +17,12 @@
 package faf
 
 import (
+	"errors"
 	"iter"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
@@ -39,9 +42,32 @@ var readDirBuffer = &sync.Pool{
 	New: func() any { return &readBuffer{make([]byte, readDirBufferSize)} },
 }
 
+// ReadDirOption controls optional behavior of [ReadDir] and its siblings.
+// Multiple options can be passed individually or combined using “|”.
+type ReadDirOption uint
+
+const (
+	// ResolveUnknownTypes resolves the types of directory entries reported by
+	// the file system as [DirEntryUnknown] by stat'ing them relative to the
+	// already open directory, without following symbolic links. Some file
+	// systems, such as certain overlay, NFS, and XFS configurations, don't
+	// return the types of directory entries, so [DirEntry.IsDir] and friends
+	// would otherwise silently report false.
+	ResolveUnknownTypes ReadDirOption = 1 << iota
+)
+
+// readDirFlags combines the individual options into a single set of flags.
+func readDirFlags(opts []ReadDirOption) (flags ReadDirOption) {
+	for _, opt := range opts {
+		flags |= opt
+	}
+	return
+}
+
 // ReadDir returns an iterator over entries of the specified directory. In case
 // the specified directory does not exist, the iterator does not produce any
-// entries.
+// entries. Use [ReadDirE] instead when the reason for not producing (any more)
+// entries matters.
 //
 // Please note that ReadDir produces [DirEntry] directory entries where their
 // Name fields are stored as []byte, referencing the underlying raw directory
@@ -49,79 +75,388 @@ var readDirBuffer = &sync.Pool{
 // iteration loop. This design avoids heap allocations and puts the need, if
 // any, into the hands of the loop body.
 //
-// Due to its Go iterator design, ReadDir needs only a single allocation per
-// full iteration, as opposed to O(n) for [os.File.ReadDir]. A second allocation
-// only happens for the first run or if there are concurrent directory reads
-// going on and a new directory entries read buffer needs to be allocated. This
-// directory entries buffer allocation behavior mimics the one exhibited by
-// os.File.ReadDir.
+// Due to its Go iterator design, ReadDir needs at most a single allocation per
+// full iteration, as opposed to O(n) for [os.File.ReadDir]. As ReadDir is
+// small enough to get inlined, the compiler usually even keeps the iterator
+// function on the stack, so there are no allocations at all. Additionally, an
+// allocation only happens for the first run or if there are concurrent
+// directory reads going on and a new directory entries read buffer needs to be
+// allocated. This directory entries buffer allocation behavior mimics the one
+// exhibited by os.File.ReadDir. Opening the directory doesn't allocate either,
+// as opposed to [unix.Open] allocating a NUL-terminated copy of the name.
 //
 // ReadDir is roughly 25% faster compared to [os.File.ReadDir] and only needs a
-// constant(!) 24 B/op heap allocation, as opposed to O(n) heap allocations for
-// the stdlib's ReadDir.
-func ReadDir(name string) iter.Seq[DirEntry] {
+// constant(!) 24 B/op heap allocation at most, as opposed to O(n) heap
+// allocations for the stdlib's ReadDir.
+func ReadDir(name string, opts ...ReadDirOption) iter.Seq[DirEntry] {
+	return ReadDirAt(unix.AT_FDCWD, name, opts...)
+}
+
+// ReadDirAt returns an iterator over entries of the specified directory, where
+// a relative directory name is relative to the directory referenced by the
+// dirfd file descriptor (instead of the current working directory). In case
+// the specified directory does not exist, the iterator does not produce any
+// entries. Passing [unix.AT_FDCWD] as dirfd makes ReadDirAt behave the same as
+// [ReadDir].
+//
+// ReadDirAt avoids the kernel having to walk the same path prefix over and
+// over again, such as when descending into subdirectories, or when reading
+// multiple directories of the same process in /proc/$PID.
+func ReadDirAt(dirfd int, name string, opts ...ReadDirOption) iter.Seq[DirEntry] {
+	flags := readDirFlags(opts)
 	return func(yield func(DirEntry) bool) {
-		fd, err := unix.Open(name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
-		if err != nil {
+		fd, errno := openat(dirfd, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		_ = readDirFd(fd, flags, yield)
+	}
+}
+
+// ReadDirFd returns an iterator over the file descriptor of the specified open
+// directory together with its entries. The directory file descriptor allows
+// the loop body to access directory entries relative to the directory without
+// having to build path strings and without the kernel having to walk the
+// directory's path again, such as using [ReadFileAt]:
+//
+//	var path faf.PathBuf
+//	for dirfd, entry := range faf.ReadDirFd("/proc") {
+//	    stat, ok := faf.ReadFileAt(dirfd, path.Reset("").Append(entry.Name).Str("/stat").String(), buff)
+//	    // ...
+//	}
+//
+// The directory file descriptor is only valid inside the loop body and must
+// not be closed by the loop body. See also [DirEntry.DirFd].
+func ReadDirFd(name string, opts ...ReadDirOption) iter.Seq2[int, DirEntry] {
+	flags := readDirFlags(opts)
+	return func(yield func(int, DirEntry) bool) {
+		fd, errno := openat(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		_ = readDirFd(fd, flags, func(dirEntry DirEntry) bool {
+			return yield(fd, dirEntry)
+		})
+	}
+}
+
+// ReadDirFrom returns an iterator over entries of the specified directory,
+// starting from the position identified by the specified offset cookie. A
+// cookie of zero starts at the beginning of the directory. Otherwise, use the
+// Off field of the last directory entry processed in order to resume reading
+// the directory with the entry following it. This allows processing very large
+// directories, such as /proc with huge numbers of processes, in chunks.
+//
+// Please note that offset cookies are opaque values that are only meaningful
+// for the same directory and might become stale when the directory changes,
+// depending on the particular file system. In case the specified directory
+// does not exist or the cookie is invalid, the iterator does not produce any
+// entries.
+func ReadDirFrom(name string, cookie int64, opts ...ReadDirOption) iter.Seq[DirEntry] {
+	flags := readDirFlags(opts)
+	return func(yield func(DirEntry) bool) {
+		fd, errno := openat(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		if _, err := unix.Seek(fd, cookie, unix.SEEK_SET); err != nil {
+			return
+		}
+		_ = readDirFd(fd, flags, yield)
+	}
+}
+
+// minDirEntrySize is the minimum size of a raw directory entry as returned by
+// the getdents64 syscall: the fixed-size header, a single character name, and
+// the terminating NUL, aligned to 8 bytes.
+const minDirEntrySize = (int(unsafe.Offsetof(unix.Dirent{}.Name)) + 2 + 7) &^ 7
+
+// dirEntrySlab is a reusable slab of directory entries, big enough to hold all
+// directory entries from a single getdents64 buffer.
+type dirEntrySlab struct{ entries []DirEntry }
+
+// pool of directory entry slabs for batched directory reading.
+var dirEntrySlabs = &sync.Pool{
+	New: func() any {
+		return &dirEntrySlab{make([]DirEntry, 0, readDirBufferSize/minDirEntrySize)}
+	},
+}
+
+// ReadDirBatches returns an iterator over batches of entries of the specified
+// directory, where each batch contains all entries decoded from a single
+// getdents64 syscall. In case the specified directory does not exist, the
+// iterator does not produce any batches.
+//
+// ReadDirBatches trades the per-entry overhead of calling the loop body of a
+// range-over-func loop for a per-batch overhead. This might help with
+// directories with thousands of entries. The batches yielded are backed by a
+// reused slab, so both the batch slices as well as the Name fields of their
+// directory entries are only valid inside the loop body.
+func ReadDirBatches(name string, opts ...ReadDirOption) iter.Seq[[]DirEntry] {
+	flags := readDirFlags(opts)
+	return func(yield func([]DirEntry) bool) {
+		fd, errno := openat(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
 			return
 		}
 		defer unix.Close(fd)
 
 		rb := readDirBuffer.Get().(*readBuffer)
 		defer readDirBuffer.Put(rb)
+		slab := dirEntrySlabs.Get().(*dirEntrySlab)
+		defer dirEntrySlabs.Put(slab)
 		buff := rb.buff
-		pos := 0
-		avail := 0
-		var dirEntry DirEntry
 		for {
-			// (re)fill the buffer when necessary...
-			if pos >= avail {
-				pos = 0
-				var err error
-				avail, err = syscall.Getdents(fd, buff)
-				if err != nil || avail <= 0 {
-					return
-				}
-			}
-			// now drain the buffer, pushing directory entries to the iterator
-			// consumer as we progress entry by entry.
-			dentry := RawDirEntry64(buff[pos:avail])
-			dentryLen, ok := dentry.Len()
-			if !ok || dentryLen > uint64(len(dentry)) {
-				return // we've fallen off the edge of the disc, erm, directory
-			}
-			pos += int(dentryLen)
-			ino, ok := dentry.Ino()
-			if !ok || ino == 0 {
-				continue
-			}
-			// Please note that we here work with the name as a byte slice
-			// instead of a string; the rationale being deferring any conversion
-			// up into the user code consuming it as this allows the compiler to
-			// see how the underlying byte slice is being used and applying
-			// optimized conversions. If we would handle name as a string here
-			// we would end up with the string escaping to the heap, so needing
-			// a heap allocation with a copy.
-			//
-			// The command "go build -gcflags '-m -l'" then confirms
-			// "string(name) does not escape".
-			name, ok := dentry.Name()
-			if !ok {
+			avail, err := syscall.Getdents(fd, buff)
+			if err != nil || avail <= 0 {
 				return
 			}
-			if string(name) == "." || string(name) == ".." {
-				continue
-			}
-			typ, ok := dentry.Type()
-			if !ok {
+			batch, err := decodeDirEntriesInto(fd, buff[:avail], flags, slab.entries[:0])
+			slab.entries = batch[:0]
+			if len(batch) > 0 && !yield(batch) {
 				return
 			}
-			dirEntry.Ino = ino
-			dirEntry.Name = name
-			dirEntry.Type = DirEntryType(typ)
-			if !yield(dirEntry) {
+			if err != nil {
 				return
 			}
 		}
 	}
 }
+
+// ReadDirBytes works like [ReadDir], but takes the directory name as a byte
+// slice. If the name is NUL-terminated, it is passed as is to the kernel,
+// otherwise a NUL-terminated copy is made on the stack.
+func ReadDirBytes(name []byte, opts ...ReadDirOption) iter.Seq[DirEntry] {
+	flags := readDirFlags(opts)
+	return func(yield func(DirEntry) bool) {
+		fd, errno := openatBytes(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		_ = readDirFd(fd, flags, yield)
+	}
+}
+
+// ReadDirE returns an iterator over entries of the specified directory, as
+// well as any error encountered while reading the directory. In contrast to
+// [ReadDir], ReadDirE thus allows callers to tell an empty directory apart from
+// a directory that cannot be read, such as due to [unix.EACCES], or a
+// directory that vanished, such as a /proc/$PID directory of a process that
+// terminated in the meantime.
+//
+// The iterator yields the directory entries with a nil error. In case of an
+// error, the iterator yields a final zero DirEntry together with the error,
+// and then stops. Errors are either [unix.Errno] values as returned by the
+// failing syscall, or [ErrMalformedDirEntry] if the kernel returned a
+// truncated or otherwise malformed directory entry.
+//
+// The same lifetime restrictions as for ReadDir apply to the directory entries
+// yielded, and ReadDirE has the same allocation behavior as ReadDir when no
+// error occurs.
+func ReadDirE(name string, opts ...ReadDirOption) iter.Seq2[DirEntry, error] {
+	flags := readDirFlags(opts)
+	return func(yield func(DirEntry, error) bool) {
+		fd, errno := openat(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			yield(DirEntry{}, errno)
+			return
+		}
+		defer unix.Close(fd)
+		if err := readDirFd(fd, flags, func(dirEntry DirEntry) bool {
+			return yield(dirEntry, nil)
+		}); err != nil {
+			yield(DirEntry{}, err)
+		}
+	}
+}
+
+// ReadDirMatch returns an iterator over only those entries of the specified
+// directory with names matching the specified shell file name pattern, using
+// the same pattern syntax as [path.Match]. The names are matched as byte
+// slices before yielding, so without converting them into strings first. In
+// case the specified directory does not exist or the pattern is malformed, the
+// iterator does not produce any entries.
+//
+// The same lifetime restrictions as for [ReadDir] apply to the directory
+// entries yielded.
+func ReadDirMatch(name string, pattern string, opts ...ReadDirOption) iter.Seq[DirEntry] {
+	flags := readDirFlags(opts)
+	return func(yield func(DirEntry) bool) {
+		p, err := CompilePattern(pattern)
+		if err != nil {
+			return
+		}
+		fd, errno := openat(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		_ = readDirFd(fd, flags, func(dirEntry DirEntry) bool {
+			if !p.Match(dirEntry.Name) {
+				return true
+			}
+			return yield(dirEntry)
+		})
+	}
+}
+
+// ErrMalformedDirEntry signals that a directory entry returned by the kernel
+// was truncated or otherwise malformed.
+var ErrMalformedDirEntry = errors.New("malformed directory entry")
+
+// readDirFd reads the directory entries from the already opened directory
+// file descriptor, yielding them one after another. It returns a non-nil error
+// only if reading the directory entries failed, but not when the consumer
+// stops the iteration early.
+func readDirFd(fd int, flags ReadDirOption, yield func(DirEntry) bool) error {
+	rb := readDirBuffer.Get().(*readBuffer)
+	defer readDirBuffer.Put(rb)
+	buff := rb.buff
+	for {
+		avail, err := syscall.Getdents(fd, buff)
+		if err != nil {
+			return err
+		}
+		if avail <= 0 {
+			return nil
+		}
+		if cont, err := decodeDirEntries(fd, buff[:avail], flags, yield); !cont {
+			return err
+		}
+	}
+}
+
+// decodeDirEntries drains the buffer filled by getdents64 from the directory
+// referenced by fd, yielding the directory entries one after another. It
+// returns true if the caller should continue reading the directory, otherwise
+// false. In the latter case a non-nil error indicates a malformed directory
+// entry, while a nil error indicates that the consumer stopped the iteration.
+func decodeDirEntries(fd int, buff []byte, flags ReadDirOption, yield func(DirEntry) bool) (bool, error) {
+	var dirEntry DirEntry
+	for pos := 0; pos < len(buff); {
+		size, valid, err := decodeDirEntry(fd, buff[pos:], flags, &dirEntry)
+		if err != nil {
+			return false, err
+		}
+		pos += size
+		if valid && !yield(dirEntry) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// decodeDirEntriesInto works like decodeDirEntries, but instead of yielding the
+// directory entries one after another, it appends them to the passed entries,
+// returning the extended entries. It returns a non-nil error in case of a
+// malformed directory entry, together with the entries decoded so far.
+func decodeDirEntriesInto(fd int, buff []byte, flags ReadDirOption, entries []DirEntry) ([]DirEntry, error) {
+	var dirEntry DirEntry
+	for pos := 0; pos < len(buff); {
+		size, valid, err := decodeDirEntry(fd, buff[pos:], flags, &dirEntry)
+		if err != nil {
+			return entries, err
+		}
+		pos += size
+		if valid {
+			entries = append(entries, dirEntry)
+		}
+	}
+	return entries, nil
+}
+
+// decodeDirEntry decodes the raw directory entry at the beginning of the buffer
+// filled by getdents64 from the directory referenced by fd into dirEntry. It
+// returns the size of the raw directory entry and whether dirEntry is valid,
+// as opposed to deleted entries and the “.” and “..” entries that are to be
+// skipped. In case of a malformed raw directory entry, it returns
+// [ErrMalformedDirEntry].
+func decodeDirEntry(fd int, buff []byte, flags ReadDirOption, dirEntry *DirEntry) (int, bool, error) {
+	dentry := RawDirEntry64(buff)
+	dentryLen, ok := dentry.Len()
+	if !ok || dentryLen == 0 || dentryLen > uint64(len(dentry)) {
+		// we've fallen off the edge of the disc, erm, directory
+		return 0, false, ErrMalformedDirEntry
+	}
+	size := int(dentryLen)
+	ino, ok := dentry.Ino()
+	if !ok || ino == 0 {
+		return size, false, nil
+	}
+	// Please note that we here work with the name as a byte slice instead of
+	// a string; the rationale being deferring any conversion up into the user
+	// code consuming it as this allows the compiler to see how the underlying
+	// byte slice is being used and applying optimized conversions. If we would
+	// handle name as a string here we would end up with the string escaping to
+	// the heap, so needing a heap allocation with a copy.
+	//
+	// The command "go build -gcflags '-m -l'" then confirms "string(name) does
+	// not escape".
+	name, ok := dentry.Name()
+	if !ok {
+		return 0, false, ErrMalformedDirEntry
+	}
+	if string(name) == "." || string(name) == ".." {
+		return size, false, nil
+	}
+	typ, ok := dentry.Type()
+	if !ok {
+		return 0, false, ErrMalformedDirEntry
+	}
+	off, ok := dentry.Off()
+	if !ok {
+		return 0, false, ErrMalformedDirEntry
+	}
+	if typ == DirEntryUnknown && flags&ResolveUnknownTypes != 0 {
+		typ = resolveType(fd, name)
+	}
+	dirEntry.Ino = ino
+	dirEntry.Off = off
+	dirEntry.Name = name
+	dirEntry.Type = typ
+	dirEntry.dirfd = fd + 1
+	return size, true, nil
+}
+
+// statxUnavailable is set when statx turned out to be unavailable, either due
+// to a pre-4.11 kernel or a seccomp profile blocking it.
+var statxUnavailable atomic.Bool
+
+// resolveType returns the type of the named directory entry inside the
+// directory referenced by dirfd, without following symbolic links. If the type
+// cannot be determined, it returns [DirEntryUnknown].
+//
+// We prefer statx over fstatat(AT_SYMLINK_NOFOLLOW), as statx allows us to ask
+// for just the file type, so file systems don't need to gather any other
+// attributes, and with AT_STATX_DONT_SYNC network file systems don't need to
+// synchronize with their servers. If statx is unavailable, we fall back to
+// fstatat.
+func resolveType(dirfd int, name []byte) DirEntryType {
+	if !statxUnavailable.Load() {
+		var stx unix.Statx_t
+		switch errno := statxBytes(dirfd, name,
+			unix.AT_SYMLINK_NOFOLLOW|unix.AT_STATX_DONT_SYNC, unix.STATX_TYPE,
+			&stx); errno {
+		case 0:
+			if stx.Mask&unix.STATX_TYPE == 0 {
+				return DirEntryUnknown
+			}
+			// The DT_xxx constants are defined to be the S_IFxxx file type
+			// bits shifted right by 12 bits.
+			return DirEntryType((uint32(stx.Mode) & unix.S_IFMT) >> 12)
+		case unix.ENOSYS, unix.EPERM:
+			statxUnavailable.Store(true)
+		default:
+			return DirEntryUnknown
+		}
+	}
+	var st unix.Stat_t
+	if unix.Fstatat(dirfd, string(name), &st, unix.AT_SYMLINK_NOFOLLOW) != nil {
+		return DirEntryUnknown
+	}
+	return DirEntryType((uint32(st.Mode) & unix.S_IFMT) >> 12)
+}
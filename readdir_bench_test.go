@@ -86,6 +86,8 @@ func BenchmarkReadDir(b *testing.B) {
 	b.Run("os.File.ReadDir", f(bmFileReadDir))
 	b.Run("os.NewFile", f(bmNewFile))
 	b.Run("faf.ReadDir", f(bmReadDir))
+	b.Run("faf.ReadDirBytes", f(bmReadDirBytes))
+	b.Run("faf.ReadDirBatches", f(bmReadDirBatches))
 }
 
 var (
@@ -137,3 +139,20 @@ func bmReadDir(b *testing.B, testdatadir string) {
 		}
 	}
 }
+
+func bmReadDirBytes(b *testing.B, testdatadir string) {
+	name := append([]byte(testdatadir), 0)
+	for n := 0; n < b.N; n++ {
+		for range faf.ReadDirBytes(name) {
+		}
+	}
+}
+
+func bmReadDirBatches(b *testing.B, testdatadir string) {
+	for n := 0; n < b.N; n++ {
+		for batch := range faf.ReadDirBatches(testdatadir) {
+			for range batch {
+			}
+		}
+	}
+}
@@ -17,9 +17,16 @@
 package faf
 
 import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+	"golang.org/x/sys/unix"
 )
 
 var _ = Describe("64bit dirents", func() {
@@ -61,4 +68,285 @@ var _ = Describe("64bit dirents", func() {
 		Expect(count).To(BeZero())
 	})
 
+	It("reads a directory given as bytes", func() {
+		names := []string{}
+		for dentry := range ReadDirBytes([]byte("./_testdata/foo\x00")) {
+			names = append(names, string(dentry.Name))
+		}
+		Expect(names).To(ConsistOf("bar", "baz"))
+
+		count := 0
+		for range ReadDirBytes([]byte("./_testdata/non-existing")) {
+			count++
+		}
+		Expect(count).To(BeZero())
+	})
+
+	It("reads a directory relative to a directory fd", func() {
+		dirfd := Successful(unix.Open("./_testdata", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0))
+		defer unix.Close(dirfd)
+
+		names := []string{}
+		for dentry := range ReadDirAt(dirfd, "foo") {
+			names = append(names, string(dentry.Name))
+		}
+		Expect(names).To(ConsistOf("bar", "baz"))
+
+		names = []string{}
+		for dentry := range ReadDirAt(dirfd, "foo/baz") {
+			names = append(names, string(dentry.Name))
+		}
+		Expect(names).To(ConsistOf(".notempty"))
+
+		count := 0
+		for range ReadDirAt(dirfd, "non-existing") {
+			count++
+		}
+		Expect(count).To(BeZero())
+	})
+
+	Context("reporting errors", func() {
+
+		It("reads the test directory correctly", func() {
+			names := []string{}
+			for dentry, err := range ReadDirE("./_testdata/foo") {
+				Expect(err).NotTo(HaveOccurred())
+				names = append(names, string(dentry.Name))
+			}
+			Expect(names).To(ConsistOf("bar", "baz"))
+		})
+
+		It("stops early", func() {
+			count := 0
+			for _, err := range ReadDirE("./_testdata/foo") {
+				Expect(err).NotTo(HaveOccurred())
+				count++
+				break
+			}
+			Expect(count).To(Equal(1))
+		})
+
+		DescribeTable("reports errors",
+			func(name string, expectedErr error) {
+				count := 0
+				var lastErr error
+				for dentry, err := range ReadDirE(name) {
+					count++
+					Expect(dentry).To(BeZero())
+					lastErr = err
+				}
+				Expect(count).To(Equal(1))
+				Expect(lastErr).To(MatchError(expectedErr))
+			},
+			Entry("non-existing directory", "./_testdata/non-existing", unix.ENOENT),
+			Entry("not a directory", "./_testdata/foo/bar", unix.ENOTDIR),
+		)
+
+		It("reports getdents errors", func() {
+			fd := Successful(unix.Open("./_testdata/foo/bar", unix.O_RDONLY|unix.O_CLOEXEC, 0))
+			defer unix.Close(fd)
+			Expect(readDirFd(fd, 0, func(DirEntry) bool { return true })).To(MatchError(unix.ENOTDIR))
+		})
+
+		It("doesn't allocate", func() {
+			Expect(testing.AllocsPerRun(100, func() {
+				for _, err := range ReadDirE("./_testdata/foo") {
+					if err != nil {
+						panic(err)
+					}
+				}
+			})).To(BeNumerically("<=", poolMissAllocs(1)))
+		})
+
+	})
+
+	It("resolves unknown directory entry types", func() {
+		dirfd := Successful(unix.Open("./_testdata/foo", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0))
+		defer unix.Close(dirfd)
+		Expect(resolveType(dirfd, []byte("bar"))).To(Equal(DirEntryRegular))
+		Expect(resolveType(dirfd, []byte("baz"))).To(Equal(DirEntryDir))
+		Expect(resolveType(dirfd, []byte("non-existing"))).To(Equal(DirEntryUnknown))
+
+		for dentry := range ReadDir("./_testdata/foo", ResolveUnknownTypes) {
+			Expect(dentry.IsUnknown()).To(BeFalse())
+		}
+	})
+
+	It("falls back to fstatat when statx is unavailable", func() {
+		statxUnavailable.Store(true)
+		defer statxUnavailable.Store(false)
+		dirfd := Successful(unix.Open("./_testdata/foo", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0))
+		defer unix.Close(dirfd)
+		Expect(resolveType(dirfd, []byte("bar"))).To(Equal(DirEntryRegular))
+		Expect(resolveType(dirfd, []byte("baz"))).To(Equal(DirEntryDir))
+		Expect(resolveType(dirfd, []byte("non-existing"))).To(Equal(DirEntryUnknown))
+	})
+
+	It("reads only matching directory entries", func() {
+		names := func(pattern string) []string {
+			names := []string{}
+			for dentry := range ReadDirMatch("./_testdata/foo", pattern) {
+				names = append(names, string(dentry.Name))
+			}
+			return names
+		}
+		Expect(names("b*")).To(ConsistOf("bar", "baz"))
+		Expect(names("ba[r]")).To(ConsistOf("bar"))
+		Expect(names("?az")).To(ConsistOf("baz"))
+		Expect(names("foo*")).To(BeEmpty())
+		Expect(names("[")).To(BeEmpty())
+
+		Expect(testing.AllocsPerRun(100, func() {
+			for range ReadDirMatch("./_testdata/foo", "ba[r]") {
+			}
+		})).To(BeNumerically("<=", poolMissAllocs(1)))
+	})
+
+	It("resumes reading a directory", func() {
+		dir := GinkgoT().TempDir()
+		for idx := range 100 {
+			Expect(os.WriteFile(filepath.Join(dir, strconv.Itoa(idx)), nil, 0644)).To(Succeed())
+		}
+
+		names := []string{}
+		cookie := int64(0)
+		for {
+			count := 0
+			for dentry := range ReadDirFrom(dir, cookie) {
+				names = append(names, string(dentry.Name))
+				cookie = dentry.Off
+				count++
+				if count == 7 {
+					break
+				}
+			}
+			if count == 0 {
+				break
+			}
+		}
+		Expect(names).To(HaveLen(100))
+		Expect(names).To(ConsistOf(func() []any {
+			all := []any{}
+			for idx := range 100 {
+				all = append(all, strconv.Itoa(idx))
+			}
+			return all
+		}()...))
+
+		count := 0
+		for range ReadDirFrom(filepath.Join(dir, "non-existing"), 0) {
+			count++
+		}
+		Expect(count).To(BeZero())
+	})
+
+	It("reads directory entries in batches", func() {
+		dir := GinkgoT().TempDir()
+		for idx := range 1000 {
+			Expect(os.WriteFile(filepath.Join(dir, strconv.Itoa(idx)), nil, 0644)).To(Succeed())
+		}
+		batches := 0
+		names := map[string]struct{}{}
+		for batch := range ReadDirBatches(dir) {
+			Expect(batch).NotTo(BeEmpty())
+			batches++
+			for _, dentry := range batch {
+				names[string(dentry.Name)] = struct{}{}
+			}
+		}
+		Expect(batches).To(BeNumerically(">", 1))
+		Expect(names).To(HaveLen(1000))
+
+		batches = 0
+		for range ReadDirBatches(dir) {
+			batches++
+			break
+		}
+		Expect(batches).To(Equal(1))
+
+		for range ReadDirBatches(filepath.Join(dir, "non-existing")) {
+			Fail("unexpected batch")
+		}
+
+		Expect(testing.AllocsPerRun(10, func() {
+			for range ReadDirBatches(dir) {
+			}
+		})).To(BeNumerically("<=", poolMissAllocs(2)))
+	})
+
+	It("stats directory entries relative to their directory", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "foo"), []byte("Hellorld!"), 0640)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(dir, "bar"), 0750)).To(Succeed())
+		Expect(os.Symlink("foo", filepath.Join(dir, "baz"))).To(Succeed())
+
+		count := 0
+		for dentry := range ReadDir(dir) {
+			count++
+			var expected unix.Stat_t
+			Expect(unix.Lstat(filepath.Join(dir, string(dentry.Name)), &expected)).To(Succeed())
+			var st unix.Stat_t
+			Expect(dentry.Stat(&st)).To(BeTrue())
+			Expect(st).To(Equal(expected))
+
+			var stx unix.Statx_t
+			Expect(dentry.Statx(unix.STATX_SIZE|unix.STATX_INO, &stx)).To(BeTrue())
+			Expect(stx.Mask & (unix.STATX_SIZE | unix.STATX_INO)).To(Equal(uint32(unix.STATX_SIZE | unix.STATX_INO)))
+			Expect(stx.Size).To(Equal(uint64(expected.Size)))
+			Expect(stx.Ino).To(Equal(dentry.Ino))
+		}
+		Expect(count).To(Equal(3))
+
+		var st unix.Stat_t
+		Expect(DirEntry{Name: []byte("foo")}.Stat(&st)).To(BeFalse())
+		s := SnapshotDir(dir)
+		Expect(s.Entry(0).Stat(&st)).To(BeFalse())
+
+		Expect(testing.AllocsPerRun(10, func() {
+			for dentry := range ReadDir(dir) {
+				if !dentry.Stat(&st) {
+					panic("stat failed")
+				}
+			}
+		})).To(BeNumerically("<=", poolMissAllocs(1)))
+	})
+
+	It("reads relative to the directory fd during iteration", func() {
+		dir := GinkgoT().TempDir()
+		for _, name := range []string{"1", "42"} {
+			Expect(os.Mkdir(filepath.Join(dir, name), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, name, "stat"), []byte(name), 0644)).To(Succeed())
+		}
+
+		var path PathBuf
+		contents := map[string]string{}
+		for dirfd, dentry := range ReadDirFd(dir) {
+			Expect(dentry.DirFd()).To(Equal(dirfd))
+			stat, ok := ReadFileAt(dirfd, path.Reset("").Append(dentry.Name).Str("/stat").String(), nil)
+			Expect(ok).To(BeTrue())
+			contents[string(dentry.Name)] = string(stat)
+		}
+		Expect(contents).To(Equal(map[string]string{"1": "1", "42": "42"}))
+
+		for range ReadDirFd(filepath.Join(dir, "non-existing")) {
+			Fail("unexpected directory entry")
+		}
+		count := 0
+		for range ReadDirFd(dir) {
+			count++
+			break
+		}
+		Expect(count).To(Equal(1))
+
+		Expect(DirEntry{}.DirFd()).To(Equal(-1))
+		Expect(SnapshotDir(dir).Entry(0).DirFd()).To(Equal(-1))
+
+		buff := make([]byte, 0, 64)
+		Expect(testing.AllocsPerRun(10, func() {
+			for dirfd, dentry := range ReadDirFd(dir) {
+				buff, _ = ReadFileAt(dirfd, path.Reset("").Append(dentry.Name).Str("/stat").String(), buff)
+			}
+		})).To(BeNumerically("<=", poolMissAllocs(1)))
+	})
+
 })
@@ -17,6 +17,8 @@
 package faf
 
 import (
+	"unsafe"
+
 	"golang.org/x/sys/unix"
 )
 
@@ -33,35 +35,196 @@ import (
 //
 // As with [os.ReadFile], reaching the end of the file is not considered to be
 // an error but normal operation, and thus not reported.
+//
+// In contrast to [unix.Open], ReadFile does not allocate a NUL-terminated copy
+// of the name on the heap (unless the name is very long), so repeated reads
+// into a sufficiently large buffer don't allocate at all.
 func ReadFile(name string, buffer []byte) ([]byte, bool) {
-	fd, err := unix.Open(name, unix.O_RDONLY, 0)
-	if err != nil {
+	return ReadFileAt(unix.AT_FDCWD, name, buffer)
+}
+
+// ReadFileAt works like [ReadFile], but a relative file name is relative to the
+// directory referenced by the dirfd file descriptor (instead of the current
+// working directory). Passing [unix.AT_FDCWD] as dirfd makes ReadFileAt behave
+// the same as ReadFile.
+//
+// For instance, ReadFileAt allows opening a /proc/$PID directory only once and
+// then reading its “stat”, “status”, and “cmdline” files relative to it,
+// without the kernel having to walk /proc/$PID each time anew.
+func ReadFileAt(dirfd int, name string, buffer []byte) ([]byte, bool) {
+	buffer, errno := readFileAt(dirfd, name, buffer)
+	return buffer, errno == 0
+}
+
+// ReadFileErrno works like [ReadFile], but instead of a bare bool returns the
+// [unix.Errno] of the failing syscall, or zero on success. This allows callers
+// to tell apart, say, a process that went away (ENOENT, ESRCH) from missing
+// privileges (EACCES, EPERM). As unix.Errno values implement errors.Is for
+// the io/fs errors, for instance errors.Is(errno, fs.ErrNotExist) works as
+// expected.
+//
+// Please note that the returned errno must be checked against zero, as
+// wrapping a zero unix.Errno into a non-nil error interface value doesn't
+// make it a nil error.
+func ReadFileErrno(name string, buffer []byte) ([]byte, unix.Errno) {
+	return readFileAt(unix.AT_FDCWD, name, buffer)
+}
+
+// ReadFileAtErrno works like [ReadFileAt], but returns the [unix.Errno] of the
+// failing syscall instead of a bare bool; see [ReadFileErrno] for details.
+func ReadFileAtErrno(dirfd int, name string, buffer []byte) ([]byte, unix.Errno) {
+	return readFileAt(dirfd, name, buffer)
+}
+
+// readFileAt reads the contents of the named file into the supplied buffer,
+// returning the contents and the errno of any failing syscall. See
+// [ReadFileAt] for details.
+func readFileAt(dirfd int, name string, buffer []byte) ([]byte, unix.Errno) {
+	fd, errno := openat(dirfd, name, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if errno != 0 {
+		return buffer, errno
+	}
+	defer unix.Close(fd)
+	return readFd(fd, buffer)
+}
+
+// ReadFileBytes works like [ReadFile], but takes the file name as a byte slice.
+// If the name is NUL-terminated, it is passed as is to the kernel, otherwise
+// a NUL-terminated copy is made on the stack.
+func ReadFileBytes(name []byte, buffer []byte) ([]byte, bool) {
+	fd, errno := openatBytes(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if errno != 0 {
 		return buffer, false
 	}
 	defer unix.Close(fd)
+	buffer, errno = readFd(fd, buffer)
+	return buffer, errno == 0
+}
 
+// ReadFileLimit works like [ReadFile], but reads at most limit bytes from the
+// named file, so that reading, say, a huge /proc/$PID/environ or a mistakenly
+// passed large regular file doesn't balloon the buffer: when ReadFileLimit
+// needs to grow the buffer, it never grows it beyond limit bytes. It returns
+// the contents, whether the contents were truncated at limit bytes because the
+// file had more to offer, and true if the file could be read. A negative limit
+// means no limit.
+func ReadFileLimit(name string, buffer []byte, limit int) (contents []byte, truncated bool, ok bool) {
+	return ReadFileLimitAt(unix.AT_FDCWD, name, buffer, limit)
+}
+
+// ReadFileLimitAt works like [ReadFileLimit], but a relative file name is
+// relative to the directory referenced by the dirfd file descriptor (instead of
+// the current working directory).
+func ReadFileLimitAt(dirfd int, name string, buffer []byte, limit int) (contents []byte, truncated bool, ok bool) {
+	fd, errno := openat(dirfd, name, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if errno != 0 {
+		return buffer, false, false
+	}
+	defer unix.Close(fd)
+	buffer, truncated, errno = readFdLimit(fd, buffer, limit)
+	return buffer, truncated, errno == 0
+}
+
+// readFd reads the contents of the file referenced by the specified file
+// descriptor into the supplied buffer, growing the buffer as necessary,
+// returning the contents and a zero errno. See [ReadFile] for details.
+func readFd(fd int, buffer []byte) ([]byte, unix.Errno) {
+	buffer, _, errno := readFdAt(fd, buffer, -1, -1)
+	return buffer, errno
+}
+
+// readFdLimit works like readFd, but reads at most limit bytes, unless limit is
+// negative. If the file has more than limit bytes to offer, readFdLimit
+// additionally reports the contents as truncated. When growing the buffer,
+// readFdLimit never grows it beyond limit bytes.
+func readFdLimit(fd int, buffer []byte, limit int) ([]byte, bool, unix.Errno) {
+	return readFdAt(fd, buffer, -1, limit)
+}
+
+// readFdAt implements readFd and readFdLimit. If off is negative, readFdAt
+// reads from the current file offset using read(2); otherwise, it reads
+// starting at off using pread(2), leaving the file offset unchanged.
+func readFdAt(fd int, buffer []byte, off int64, limit int) ([]byte, bool, unix.Errno) {
 	// If no backing buffer or a buffer with too small capacity was supplied,
 	// set up a new initial buffer.
 	size := 512
+	if limit >= 0 && limit < size {
+		size = limit
+	}
 	buffer = buffer[:0]
 	if size > cap(buffer) {
 		buffer = make([]byte, 0, size)
 	}
 
 	// Read in file content chunk by chunk, growing the backing buffer's
-	// capacity as needed.
+	// capacity as needed, but never reading beyond limit bytes.
 	for {
-		n, err := unix.Read(fd, buffer[len(buffer):cap(buffer)])
-		buffer = buffer[:len(buffer)+n]
-		if err != nil {
-			return buffer, false
+		chunk := buffer[len(buffer):cap(buffer)]
+		if limit >= 0 {
+			if len(buffer) == limit {
+				// Probe for a single further byte in order to tell a file of
+				// exactly limit bytes apart from a truncated one.
+				var probe [1]byte
+				n, errno := readChunk(fd, probe[:], off, len(buffer))
+				if errno != 0 {
+					return buffer, false, errno
+				}
+				return buffer, n != 0, 0
+			}
+			if len(chunk) > limit-len(buffer) {
+				chunk = chunk[:limit-len(buffer)]
+			}
+		}
+		n, errno := readChunk(fd, chunk, off, len(buffer))
+		if errno != 0 {
+			return buffer, false, errno
 		}
+		buffer = buffer[:len(buffer)+n]
 		if n == 0 {
-			return buffer, true
+			return buffer, false, 0
+		}
+		if len(buffer) < cap(buffer) || len(buffer) == limit {
+			continue
 		}
-		if len(buffer) == cap(buffer) {
+		if limit < 0 {
 			d := append(buffer[:cap(buffer)], 0)
 			buffer = d[:len(buffer)]
+			continue
 		}
+		d := make([]byte, len(buffer), min(2*cap(buffer), limit))
+		copy(d, buffer)
+		buffer = d
+	}
+}
+
+// readChunk reads into p using read(2) if off is negative, or otherwise reads
+// at off+pos without changing the file offset. It issues the raw syscalls, so
+// that buffers on the stack don't escape to the heap, not even in race builds
+// with the instrumented syscall wrappers of the unix package.
+//
+// Instead of pread64 we use preadv with a single iovec, as preadv takes the
+// offset split into a low and high word on all architectures, whereas the
+// argument passing of pread64 differs between 32 and 64 bit architectures.
+func readChunk(fd int, p []byte, off int64, pos int) (int, unix.Errno) {
+	var bp unsafe.Pointer
+	if len(p) > 0 {
+		bp = unsafe.Pointer(&p[0])
+	}
+	if off < 0 {
+		n, _, errno := unix.Syscall(unix.SYS_READ, uintptr(fd), uintptr(bp), uintptr(len(p)))
+		if errno != 0 {
+			return 0, errno
+		}
+		return int(n), 0
+	}
+	iov := unix.Iovec{Base: (*byte)(bp)}
+	iov.SetLen(len(p))
+	at := off + int64(pos)
+	n, _, errno := unix.Syscall6(unix.SYS_PREADV,
+		uintptr(fd), uintptr(unsafe.Pointer(&iov)), 1, uintptr(at), uintptr(at>>32),
+		0)
+	if errno != 0 {
+		return 0, errno
 	}
+	return int(n), 0
 }
@@ -0,0 +1,74 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+/*
+
+go test -bench=ReadFile -run=^$ -cpu=1,4 -benchmem
+
+goos: linux
+goarch: amd64
+pkg: github.com/thediveo/faf
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkReadFile/os.ReadFile                     100071             10458 ns/op           10560 B/op          5 allocs/op
+BenchmarkReadFile/os.ReadFile-4                    61719             20297 ns/op           10560 B/op          5 allocs/op
+BenchmarkReadFile/faf.ReadFile                    547844              2328 ns/op               0 B/op          0 allocs/op
+BenchmarkReadFile/faf.ReadFile-4                  434348              2559 ns/op               0 B/op          0 allocs/op
+BenchmarkReadFile/faf.ReadFileBytes               497446              2544 ns/op               0 B/op          0 allocs/op
+BenchmarkReadFile/faf.ReadFileBytes-4             490039              3350 ns/op               0 B/op          0 allocs/op
+
+*/
+
+package faf_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/thediveo/faf"
+)
+
+var contents []byte
+
+func BenchmarkReadFile(b *testing.B) {
+	b.Run("os.ReadFile", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			var err error
+			contents, err = os.ReadFile("LICENSE")
+			if err != nil {
+				b.Fatalf("cannot read file, reason: %s", err)
+			}
+		}
+	})
+	b.Run("faf.ReadFile", func(b *testing.B) {
+		buff := make([]byte, 0, 16384)
+		for n := 0; n < b.N; n++ {
+			contents, ok = faf.ReadFile("LICENSE", buff)
+			if !ok {
+				b.Fatalf("cannot read file")
+			}
+		}
+	})
+	b.Run("faf.ReadFileBytes", func(b *testing.B) {
+		buff := make([]byte, 0, 16384)
+		name := []byte("LICENSE\x00")
+		for n := 0; n < b.N; n++ {
+			contents, ok = faf.ReadFileBytes(name, buff)
+			if !ok {
+				b.Fatalf("cannot read file")
+			}
+		}
+	})
+}
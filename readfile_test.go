@@ -17,13 +17,17 @@
 package faf
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"testing"
 	"unsafe"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/thediveo/fdooze"
 	. "github.com/thediveo/success"
+	"golang.org/x/sys/unix"
 )
 
 var _ = Describe("ReadFile", func() {
@@ -44,6 +48,12 @@ var _ = Describe("ReadFile", func() {
 		Expect(buff).To(BeNil())
 	})
 
+	It("reports when given a directory", func() {
+		buff, ok := ReadFile("./_testdata", nil)
+		Expect(ok).To(BeFalse())
+		Expect(buff).NotTo(BeNil())
+	})
+
 	It("reads a file correctly, growing a buffer as needed", func() {
 		osrContents := Successful(os.ReadFile("LICENSE"))
 		contents := Ok(ReadFile("LICENSE", nil))
@@ -58,4 +68,117 @@ var _ = Describe("ReadFile", func() {
 		Expect(contents).To(Equal(osrContents))
 	})
 
+	It("reads a file given as bytes", func() {
+		osrContents := Successful(os.ReadFile("LICENSE"))
+		Expect(Ok(ReadFileBytes([]byte("LICENSE"), nil))).To(Equal(osrContents))
+		Expect(Ok(ReadFileBytes([]byte("LICENSE\x00"), nil))).To(Equal(osrContents))
+
+		_, ok := ReadFileBytes([]byte("LICENSE\x00\x00"), nil)
+		Expect(ok).To(BeFalse())
+		_, ok = ReadFileBytes([]byte("./_testdata/non-existing"), nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("doesn't allocate when reading into a large enough buffer", func() {
+		buff := make([]byte, 0, 16384)
+		Expect(testing.AllocsPerRun(100, func() {
+			buff, _ = ReadFile("LICENSE", buff)
+		})).To(BeZero())
+		name := []byte("LICENSE\x00")
+		Expect(testing.AllocsPerRun(100, func() {
+			buff, _ = ReadFileBytes(name, buff)
+		})).To(BeZero())
+	})
+
+	It("reads a file relative to a directory fd", func() {
+		dirfd := Successful(unix.Open(".", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0))
+		defer unix.Close(dirfd)
+
+		osrContents := Successful(os.ReadFile("LICENSE"))
+		Expect(Ok(ReadFileAt(dirfd, "LICENSE", nil))).To(Equal(osrContents))
+
+		_, ok := ReadFileAt(dirfd, "_testdata/non-existing", nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports errnos", func() {
+		osrContents := Successful(os.ReadFile("LICENSE"))
+		contents, errno := ReadFileErrno("LICENSE", nil)
+		Expect(errno).To(BeZero())
+		Expect(contents).To(Equal(osrContents))
+
+		_, errno = ReadFileErrno("./_testdata/non-existing", nil)
+		Expect(errno).To(Equal(unix.ENOENT))
+		Expect(errors.Is(errno, fs.ErrNotExist)).To(BeTrue())
+		_, errno = ReadFileErrno("./_testdata", nil)
+		Expect(errno).To(Equal(unix.EISDIR))
+
+		dirfd := Successful(unix.Open(".", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0))
+		defer unix.Close(dirfd)
+		contents, errno = ReadFileAtErrno(dirfd, "LICENSE", nil)
+		Expect(errno).To(BeZero())
+		Expect(contents).To(Equal(osrContents))
+		_, errno = ReadFileAtErrno(dirfd, "_testdata/non-existing", nil)
+		Expect(errno).To(Equal(unix.ENOENT))
+	})
+
+	It("doesn't allocate when reporting errnos", func() {
+		buff := make([]byte, 0, 16384)
+		Expect(testing.AllocsPerRun(100, func() {
+			buff, _ = ReadFileErrno("LICENSE", buff)
+		})).To(BeZero())
+		Expect(testing.AllocsPerRun(100, func() {
+			_, _ = ReadFileErrno("./_testdata/non-existing", buff)
+		})).To(BeZero())
+	})
+
+	const exactFileSize = -2 // placeholder for the limit to be LICENSE's size
+
+	DescribeTable("reading a file up to a limit",
+		func(limit int, expectedLen int, expectedTruncated bool) {
+			osrContents := Successful(os.ReadFile("LICENSE"))
+			if limit == exactFileSize {
+				limit = len(osrContents)
+			}
+			if expectedLen < 0 {
+				expectedLen = len(osrContents)
+			}
+			contents, truncated, ok := ReadFileLimit("LICENSE", nil, limit)
+			Expect(ok).To(BeTrue())
+			Expect(truncated).To(Equal(expectedTruncated))
+			Expect(contents).To(Equal(osrContents[:expectedLen]))
+		},
+		Entry("nothing", 0, 0, true),
+		Entry("less than a chunk", 42, 42, true),
+		Entry("several chunks", 2000, 2000, true),
+		Entry("exactly the file size", exactFileSize, -1, false),
+		Entry("more than the file size", 1<<20, -1, false),
+		Entry("without limit", -1, -1, false),
+	)
+
+	It("doesn't grow the buffer beyond the limit", func() {
+		for _, limit := range []int{42, 512, 1000, 2000, 4096} {
+			contents, truncated, ok := ReadFileLimit("LICENSE", nil, limit)
+			Expect(ok).To(BeTrue())
+			Expect(truncated).To(BeTrue())
+			Expect(contents).To(HaveLen(limit))
+			Expect(cap(contents)).To(Equal(limit), "limit %d", limit)
+		}
+	})
+
+	It("reports limited reading failures", func() {
+		_, truncated, ok := ReadFileLimit("./_testdata/non-existing", nil, 42)
+		Expect(ok).To(BeFalse())
+		Expect(truncated).To(BeFalse())
+		_, _, ok = ReadFileLimit("./_testdata", nil, 42)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("doesn't allocate when reading up to a limit into a large enough buffer", func() {
+		buff := make([]byte, 0, 1024)
+		Expect(testing.AllocsPerRun(100, func() {
+			buff, _, _ = ReadFileLimit("LICENSE", buff, 1000)
+		})).To(BeZero())
+	})
+
 })
@@ -0,0 +1,190 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// readFileBufferSize is the initial capacity of the pooled buffers used by
+// [ReadFiles] for reading file contents into. Buffers grow as necessary and
+// are then returned to the pool with their grown capacity.
+const readFileBufferSize = 4096
+
+// readFileBuffers keeps the buffers for concurrently reading files, so that
+// reading many files in bulk avoids repeatedly allocating and zeroing buffers.
+var readFileBuffers = &sync.Pool{
+	New: func() any { return &readBuffer{make([]byte, 0, readFileBufferSize)} },
+}
+
+// readFileJob is a file to be read by one of the workers of [ReadFiles], with
+// seq numbering the file in the order of the supplied paths.
+type readFileJob struct {
+	seq  uint64
+	path string
+}
+
+// readFileResult is the outcome of reading a file; rb is nil if the file
+// couldn't be read.
+type readFileResult struct {
+	readFileJob
+	rb *readBuffer
+}
+
+// ReadFiles returns an iterator over the paths and contents of the files
+// specified by the paths iterator, reading the files concurrently using a
+// bounded pool of workers goroutines. If workers is zero or negative, the
+// number of workers defaults to [runtime.GOMAXPROCS]. Files that cannot be read
+// are silently skipped. The order in which files are yielded is unspecified;
+// use [ReadFilesOrdered] to get them in the order of the supplied paths.
+//
+// The contents yielded are only valid inside the loop body, as their buffers
+// get returned to a buffer pool when the loop body finishes, so they can be
+// reused for reading further files. Copy the contents if you need them after
+// the loop body.
+//
+// The paths iterator is consumed from a separate goroutine. Breaking out of the
+// loop early stops reading further files and doesn't leak goroutines.
+func ReadFiles(paths iter.Seq[string], workers int) iter.Seq2[string, []byte] {
+	return readFiles(paths, workers, false)
+}
+
+// ReadFilesOrdered works like [ReadFiles], but yields the files in the order of
+// the supplied paths. As a slow read holds back yielding the files following
+// it, the number of files read ahead is bounded.
+func ReadFilesOrdered(paths iter.Seq[string], workers int) iter.Seq2[string, []byte] {
+	return readFiles(paths, workers, true)
+}
+
+// readFiles implements [ReadFiles] and [ReadFilesOrdered].
+func readFiles(paths iter.Seq[string], workers int, ordered bool) iter.Seq2[string, []byte] {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return func(yield func(string, []byte) bool) {
+		done := make(chan struct{})
+		jobs := make(chan readFileJob)
+		results := make(chan readFileResult, workers)
+		// tokens bounds the number of files in flight, that is, read (ahead)
+		// but not yet yielded, and thus the number of buffers in use.
+		tokens := make(chan struct{}, 2*workers)
+
+		var wg sync.WaitGroup
+		wg.Add(1 + workers)
+		go func() {
+			defer wg.Done()
+			defer close(jobs)
+			var seq uint64
+			for path := range paths {
+				select {
+				case tokens <- struct{}{}:
+				case <-done:
+					return
+				}
+				select {
+				case jobs <- readFileJob{seq: seq, path: path}:
+				case <-done:
+					return
+				}
+				seq++
+			}
+		}()
+		for range workers {
+			go func() {
+				defer wg.Done()
+				for job := range jobs {
+					res := readFileResult{readFileJob: job}
+					rb := readFileBuffers.Get().(*readBuffer)
+					if contents, ok := ReadFile(job.path, rb.buff); ok {
+						rb.buff = contents
+						res.rb = rb
+					} else {
+						readFileBuffers.Put(rb)
+					}
+					select {
+					case results <- res:
+					case <-done:
+						if res.rb != nil {
+							readFileBuffers.Put(res.rb)
+						}
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+		// When the loop body breaks early, tell the feeder and workers to stop
+		// and wait for them to finish, recycling any buffers still in flight.
+		defer func() {
+			close(done)
+			for res := range results {
+				if res.rb != nil {
+					readFileBuffers.Put(res.rb)
+				}
+			}
+		}()
+
+		// emit yields a single result, if successfully read, and then releases
+		// its buffer and token.
+		emit := func(res readFileResult) bool {
+			defer func() { <-tokens }()
+			if res.rb == nil {
+				return true
+			}
+			defer readFileBuffers.Put(res.rb)
+			return yield(res.path, res.rb.buff)
+		}
+
+		if !ordered {
+			for res := range results {
+				if !emit(res) {
+					return
+				}
+			}
+			return
+		}
+		var next uint64
+		pending := map[uint64]readFileResult{}
+		for res := range results {
+			if res.seq != next {
+				pending[res.seq] = res
+				continue
+			}
+			for {
+				if !emit(res) {
+					for _, res := range pending {
+						if res.rb != nil {
+							readFileBuffers.Put(res.rb)
+						}
+					}
+					return
+				}
+				next++
+				var ok bool
+				if res, ok = pending[next]; !ok {
+					break
+				}
+				delete(pending, next)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gleak"
+	. "github.com/thediveo/fdooze"
+)
+
+var _ = Describe("reading files concurrently", func() {
+
+	var dir string
+	var names []string
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		goodgos := Goroutines()
+		DeferCleanup(func() {
+			Eventually(Goroutines).ShouldNot(HaveLeaked(goodgos))
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+
+		dir = GinkgoT().TempDir()
+		names = nil
+		for i := range 100 {
+			name := filepath.Join(dir, strconv.Itoa(i))
+			Expect(os.WriteFile(name, []byte("file "+strconv.Itoa(i)), 0644)).To(Succeed())
+			names = append(names, name)
+		}
+	})
+
+	It("reads files, skipping unreadable ones", func() {
+		paths := append(slices.Clone(names), filepath.Join(dir, "non-existing"), dir)
+		contents := map[string]string{}
+		for path, b := range ReadFiles(slices.Values(paths), 4) {
+			Expect(contents).NotTo(HaveKey(path))
+			contents[path] = string(b)
+		}
+		Expect(contents).To(HaveLen(len(names)))
+		for i, name := range names {
+			Expect(contents).To(HaveKeyWithValue(name, "file "+strconv.Itoa(i)))
+		}
+	})
+
+	It("reads files in order", func() {
+		paths := slices.Clone(names)
+		paths = slices.Insert(paths, 42, filepath.Join(dir, "non-existing"))
+		var readnames, contents []string
+		for path, b := range ReadFilesOrdered(slices.Values(paths), 0) {
+			readnames = append(readnames, path)
+			contents = append(contents, string(b))
+		}
+		Expect(readnames).To(Equal(names))
+		for i := range names {
+			Expect(contents[i]).To(Equal("file " + strconv.Itoa(i)))
+		}
+	})
+
+	It("reads an empty list of files", func() {
+		for range ReadFiles(slices.Values([]string{}), 2) {
+			Fail("unexpected file")
+		}
+		for range ReadFilesOrdered(slices.Values([]string{}), 2) {
+			Fail("unexpected file")
+		}
+	})
+
+	DescribeTable("stops early without leaking",
+		func(readFiles func(paths []string) iter.Seq2[string, []byte]) {
+			count := 0
+			for range readFiles(names) {
+				count++
+				if count == 10 {
+					break
+				}
+			}
+			Expect(count).To(Equal(10))
+		},
+		Entry("unordered", func(paths []string) iter.Seq2[string, []byte] {
+			return ReadFiles(slices.Values(paths), 3)
+		}),
+		Entry("ordered", func(paths []string) iter.Seq2[string, []byte] {
+			return ReadFilesOrdered(slices.Values(paths), 3)
+		}),
+	)
+
+	It("reads procfs files in bulk", func() {
+		var paths []string
+		for pid := range PIDs("/proc") {
+			paths = append(paths, "/proc/"+strconv.FormatUint(pid, 10)+"/stat")
+		}
+		count := 0
+		for _, b := range ReadFiles(slices.Values(paths), 0) {
+			Expect(b).To(MatchRegexp(`^\d+ \(`))
+			count++
+		}
+		Expect(count).To(BeNumerically(">", 0))
+	})
+
+})
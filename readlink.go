@@ -0,0 +1,65 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Readlink reads the target of the named symbolic link into the supplied
+// buffer, growing the buffer as necessary, returning the link target and
+// true. Only the capacity of the passed buffer matters, the buffer's current
+// length gets ignored. If the link cannot be read for whatever reason,
+// Readlink returns false. As with [ReadFile], the most recent buffer is
+// returned even in case of error.
+//
+// In contrast to [os.Readlink], Readlink doesn't allocate as long as the
+// buffer's capacity is sufficient, so it is suitable for reading thousands of
+// symbolic links, such as /proc/$PID/ns/*, /proc/$PID/exe, and
+// /proc/$PID/fd/*.
+func Readlink(name string, buffer []byte) ([]byte, bool) {
+	return ReadlinkAt(unix.AT_FDCWD, name, buffer)
+}
+
+// ReadlinkAt works like [Readlink], but a relative link name is relative to
+// the directory referenced by the dirfd file descriptor (instead of the current
+// working directory). Passing [unix.AT_FDCWD] as dirfd makes ReadlinkAt behave
+// the same as Readlink.
+func ReadlinkAt(dirfd int, name string, buffer []byte) ([]byte, bool) {
+	// If no backing buffer or a buffer with too small capacity was supplied,
+	// set up a new initial buffer.
+	size := 128
+	buffer = buffer[:0]
+	if size > cap(buffer) {
+		buffer = make([]byte, 0, size)
+	}
+
+	// As readlink silently truncates link targets that don't fit into the
+	// buffer, we need to retry with a larger buffer until the link target fits
+	// with room to spare.
+	for {
+		n, errno := readlinkat(dirfd, name, buffer[:cap(buffer)])
+		if errno != 0 {
+			return buffer, false
+		}
+		if n < cap(buffer) {
+			return buffer[:n], true
+		}
+		d := append(buffer[:cap(buffer)], 0)
+		buffer = d[:0]
+	}
+}
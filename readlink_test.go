@@ -0,0 +1,76 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("Readlink", func() {
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+	})
+
+	It("reports when given a non-link", func() {
+		buff, ok := Readlink("./_testdata/non-existing", nil)
+		Expect(ok).To(BeFalse())
+		Expect(buff).To(BeEmpty())
+
+		_, ok = Readlink("./_testdata/foo/bar", nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reads links, growing the buffer as needed", func() {
+		dir := GinkgoT().TempDir()
+		long := strings.Repeat("x", 1000)
+		Expect(os.Symlink(long, filepath.Join(dir, "long"))).To(Succeed())
+		Expect(os.Symlink("short", filepath.Join(dir, "short"))).To(Succeed())
+
+		Expect(Ok(Readlink(filepath.Join(dir, "long"), nil))).To(Equal([]byte(long)))
+		Expect(Ok(Readlink(filepath.Join(dir, "long"), make([]byte, 0, 1000)))).To(Equal([]byte(long)))
+		Expect(Ok(Readlink(filepath.Join(dir, "short"), nil))).To(Equal([]byte("short")))
+
+		dirfd := Successful(unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0))
+		defer unix.Close(dirfd)
+		Expect(Ok(ReadlinkAt(dirfd, "short", nil))).To(Equal([]byte("short")))
+		_, ok := ReadlinkAt(dirfd, "non-existing", nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reads procfs links without allocating", func() {
+		expected := Successful(os.Readlink("/proc/self/ns/net"))
+		buff := make([]byte, 0, 128)
+		Expect(testing.AllocsPerRun(100, func() {
+			buff, _ = Readlink("/proc/self/ns/net", buff)
+		})).To(BeZero())
+		Expect(string(buff)).To(Equal(expected))
+	})
+
+})
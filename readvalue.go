@@ -0,0 +1,112 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// valueBufferSize is the size of the stack buffer for reading single-value
+// files into; files with longer contents are considered to be invalid.
+const valueBufferSize = 64
+
+// ReadUint reads the named file containing a single decimal number, such as
+// /proc/sys/kernel/pid_max or /proc/$PID/oom_score, returning the number and
+// true. Trailing whitespace, such as a final newline, is ignored. If the file
+// cannot be read or doesn't contain a valid number, ReadUint returns zero and
+// false. ReadUint reads the file into a stack buffer and thus doesn't allocate
+// when successful.
+func ReadUint(name string) (uint64, bool) {
+	var buff [valueBufferSize]byte
+	value, ok := readValue(name, buff[:0])
+	if !ok {
+		return 0, false
+	}
+	return ParseUint(value)
+}
+
+// ReadHexUint works like [ReadUint], but reads a single hexadecimal number with
+// an optional “0x” prefix, such as the vendor and device IDs of PCI devices in
+// sysfs.
+func ReadHexUint(name string) (uint64, bool) {
+	var buff [valueBufferSize]byte
+	value, ok := readValue(name, buff[:0])
+	if !ok {
+		return 0, false
+	}
+	if len(value) > 2 && value[0] == '0' && (value[1] == 'x' || value[1] == 'X') {
+		value = value[2:]
+	}
+	return ParseHexUint(value)
+}
+
+// ReadInt works like [ReadUint], but reads a single decimal number with an
+// optional leading “-” sign, such as /proc/$PID/oom_score_adj.
+func ReadInt(name string) (int64, bool) {
+	var buff [valueBufferSize]byte
+	value, ok := readValue(name, buff[:0])
+	if !ok {
+		return 0, false
+	}
+	return ParseInt(value)
+}
+
+// ReadBool works like [ReadUint], but reads a single boolean value represented
+// either as “1” and “0”, such as in /sys/devices/system/cpu/cpu1/online, or as
+// “Y” and “N”, such as for boolean module parameters in
+// /sys/module/$MODULE/parameters. It returns the boolean value and true, or
+// false and false if the file cannot be read or doesn't contain a valid boolean
+// value.
+func ReadBool(name string) (value bool, ok bool) {
+	var buff [valueBufferSize]byte
+	contents, ok := readValue(name, buff[:0])
+	if !ok || len(contents) != 1 {
+		return false, false
+	}
+	switch contents[0] {
+	case '1', 'Y', 'y':
+		return true, true
+	case '0', 'N', 'n':
+		return false, true
+	}
+	return false, false
+}
+
+// readValue reads the contents of the named file into the supplied buffer
+// without growing it, returning the contents with any trailing whitespace
+// removed and true. It returns false if the file cannot be read or the
+// contents don't fit into the buffer.
+func readValue(name string, buffer []byte) ([]byte, bool) {
+	fd, errno := openat(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if errno != 0 {
+		return nil, false
+	}
+	defer unix.Close(fd)
+	contents, truncated, errno := readFdLimit(fd, buffer, cap(buffer))
+	if errno != 0 || truncated {
+		return nil, false
+	}
+	for len(contents) > 0 {
+		switch contents[len(contents)-1] {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+			contents = contents[:len(contents)-1]
+			continue
+		}
+		break
+	}
+	return contents, true
+}
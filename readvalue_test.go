@@ -0,0 +1,144 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("reading single-value files", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+		dir = GinkgoT().TempDir()
+	})
+
+	valueFile := func(contents string) string {
+		path := filepath.Join(dir, "value")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	It("reports unreadable files", func() {
+		for _, name := range []string{filepath.Join(dir, "non-existing"), dir} {
+			_, ok := ReadUint(name)
+			Expect(ok).To(BeFalse())
+			_, ok = ReadHexUint(name)
+			Expect(ok).To(BeFalse())
+			_, ok = ReadInt(name)
+			Expect(ok).To(BeFalse())
+			_, ok = ReadBool(name)
+			Expect(ok).To(BeFalse())
+		}
+	})
+
+	DescribeTable("reading unsigned numbers",
+		func(contents string, expected uint64, expectedOk bool) {
+			v, ok := ReadUint(valueFile(contents))
+			Expect(ok).To(Equal(expectedOk))
+			Expect(v).To(Equal(expected))
+		},
+		Entry(nil, "42\n", uint64(42), true),
+		Entry(nil, "42", uint64(42), true),
+		Entry(nil, "42 \t\n\n", uint64(42), true),
+		Entry(nil, "18446744073709551615\n", uint64(math.MaxUint64), true),
+		Entry(nil, "", uint64(0), false),
+		Entry(nil, "\n", uint64(0), false),
+		Entry(nil, " 42\n", uint64(0), false),
+		Entry(nil, "42 43\n", uint64(0), false),
+		Entry(nil, "-42\n", uint64(0), false),
+		Entry(nil, strings.Repeat("0", valueBufferSize)+"42\n", uint64(0), false),
+	)
+
+	DescribeTable("reading hexadecimal numbers",
+		func(contents string, expected uint64, expectedOk bool) {
+			v, ok := ReadHexUint(valueFile(contents))
+			Expect(ok).To(Equal(expectedOk))
+			Expect(v).To(Equal(expected))
+		},
+		Entry(nil, "c0ffee\n", uint64(0xc0ffee), true),
+		Entry(nil, "0x8086\n", uint64(0x8086), true),
+		Entry(nil, "0XABCD", uint64(0xabcd), true),
+		Entry(nil, "0x\n", uint64(0), false),
+		Entry(nil, "xyz\n", uint64(0), false),
+	)
+
+	DescribeTable("reading signed numbers",
+		func(contents string, expected int64, expectedOk bool) {
+			v, ok := ReadInt(valueFile(contents))
+			Expect(ok).To(Equal(expectedOk))
+			Expect(v).To(Equal(expected))
+		},
+		Entry(nil, "42\n", int64(42), true),
+		Entry(nil, "-1000\n", int64(-1000), true),
+		Entry(nil, "-\n", int64(0), false),
+		Entry(nil, "+42\n", int64(0), false),
+	)
+
+	DescribeTable("reading booleans",
+		func(contents string, expected bool, expectedOk bool) {
+			v, ok := ReadBool(valueFile(contents))
+			Expect(ok).To(Equal(expectedOk))
+			Expect(v).To(Equal(expected))
+		},
+		Entry(nil, "1\n", true, true),
+		Entry(nil, "0\n", false, true),
+		Entry(nil, "Y\n", true, true),
+		Entry(nil, "N\n", false, true),
+		Entry(nil, "y", true, true),
+		Entry(nil, "n", false, true),
+		Entry(nil, "", false, false),
+		Entry(nil, "2\n", false, false),
+		Entry(nil, "YES\n", false, false),
+	)
+
+	It("reads procfs values", func() {
+		pidMax := Successful(strconv.ParseUint(
+			strings.TrimSpace(string(Successful(os.ReadFile("/proc/sys/kernel/pid_max")))), 10, 64))
+		Expect(Ok(ReadUint("/proc/sys/kernel/pid_max"))).To(Equal(pidMax))
+		Expect(Ok(ReadInt("/proc/self/oom_score_adj"))).To(BeNumerically(">=", -1000))
+	})
+
+	It("doesn't allocate", func() {
+		path := valueFile("42\n")
+		Expect(testing.AllocsPerRun(100, func() {
+			_, _ = ReadUint(path)
+			_, _ = ReadHexUint(path)
+			_, _ = ReadInt(path)
+		})).To(BeZero())
+		path = valueFile("Y\n")
+		Expect(testing.AllocsPerRun(100, func() {
+			_, _ = ReadBool(path)
+		})).To(BeZero())
+	})
+
+})
@@ -0,0 +1,477 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"iter"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ringReadBufferSize is the size of the per-file read buffer of a [Ring]. Files
+// not fitting into this buffer are re-read using [ReadFile] instead.
+const ringReadBufferSize = 4096
+
+// ringDefaultBatch is the default number of files read per batch by a [Ring].
+const ringDefaultBatch = 64
+
+// ringMaxBatch limits the number of files read per batch, as each file needs
+// three submission queue entries and the kernel caps them at 32768.
+const ringMaxBatch = 32768 / 3
+
+// io_uring ABI definitions, as golang.org/x/sys/unix doesn't provide them; see
+// include/uapi/linux/io_uring.h.
+const (
+	ioringOffSqRing = 0
+	ioringOffCqRing = 0x8000000
+	ioringOffSqes   = 0x10000000
+
+	ioringSetupClamp      = 1 << 4
+	ioringFeatSingleMmap  = 1 << 0
+	ioringEnterGetevents  = 1 << 0
+	ioringRegisterFiles   = 2
+	ioringRegisterProbe   = 8
+	ioringOpSupported     = 1 << 0
+	iosqeFixedFile        = 1 << 0
+	iosqeIoLink           = 1 << 2
+	iosqeIoHardlink       = 1 << 3
+	ioringOpOpenat        = 18
+	ioringOpClose         = 19
+	ioringOpRead          = 22
+	ioringProbeOpsMaxSize = 256
+)
+
+// ringOpen, ringRead, and ringClose tag the user data of submission queue
+// entries with the operation, the remaining bits hold the file index in the
+// batch.
+const (
+	ringOpen = iota
+	ringRead
+	ringClose
+	ringOpBits = 2
+	ringOpMask = 1<<ringOpBits - 1
+)
+
+type ioSqringOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type ioCqringOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type ioUringParams struct {
+	sqEntries, cqEntries, flags, sqThreadCPU, sqThreadIdle, features, wqFd uint32
+	resv                                                                   [3]uint32
+	sqOff                                                                  ioSqringOffsets
+	cqOff                                                                  ioCqringOffsets
+}
+
+type ioUringSqe struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	opFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	fileIndex   uint32
+	addr3       uint64
+	_           uint64
+}
+
+type ioUringCqe struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+type ioUringProbeOp struct {
+	op    uint8
+	resv  uint8
+	flags uint16
+	resv2 uint32
+}
+
+type ioUringProbe struct {
+	lastOp uint8
+	opsLen uint8
+	resv   uint16
+	resv2  [3]uint32
+	ops    [ioringProbeOpsMaxSize]ioUringProbeOp
+}
+
+// Ring reads files in batches using io_uring, submitting a linked openat, read,
+// and close operation per file, so that reading many (small) files, such as
+// when scanning all processes in procfs, needs only a single io_uring_enter
+// syscall per batch instead of an open, read, and close syscall per file. The
+// files are opened as “direct descriptors” that never enter the process' file
+// descriptor table.
+//
+// When io_uring is unavailable, such as on kernels before 5.15, when disabled
+// by the kernel.io_uring_disabled sysctl, or when restricted by a seccomp
+// profile, a Ring transparently falls back to reading the files one by one
+// using [ReadFile].
+//
+// A Ring must not be used concurrently from multiple goroutines.
+type Ring struct {
+	fd    int // io_uring fd, or -1 in fallback mode.
+	batch int
+
+	sqRing, cqRing, sqesMmap []byte
+
+	sqTail, sqMask *uint32
+	sqArray        unsafe.Pointer
+	sqes           unsafe.Pointer
+	cqHead, cqTail *uint32
+	cqMask         *uint32
+	cqes           unsafe.Pointer
+
+	buffers []byte   // per-file read buffers of ringReadBufferSize each.
+	names   []byte   // NUL-terminated names of the files in the current batch.
+	paths   []string // paths of the files in the current batch.
+	nameOff []int    // offsets of the paths' NUL-terminated names into names.
+	results []int32  // read results of the files in the current batch.
+	spill   []byte   // buffer for re-reading files too large for a buffer.
+	retired [][]byte // buffers kept alive after io_uring failed; see retire.
+}
+
+// NewRing returns a new Ring reading up to batch files per io_uring_enter
+// syscall; a batch of zero or less picks a default. If io_uring isn't
+// available, the returned Ring falls back to reading files using [ReadFile].
+// Use [Ring.Uring] to tell whether a Ring actually uses io_uring.
+//
+// The Ring should be closed using [Ring.Close] when not needed anymore in order
+// to release the io_uring instance.
+func NewRing(batch int) *Ring {
+	if batch <= 0 {
+		batch = ringDefaultBatch
+	}
+	batch = min(batch, ringMaxBatch)
+	r := &Ring{fd: -1, batch: batch}
+	if !r.setup() {
+		r.retire()
+	}
+	return r
+}
+
+// Uring returns true if this Ring reads files using io_uring, and false if it
+// falls back to [ReadFile].
+func (r *Ring) Uring() bool { return r.fd >= 0 }
+
+// Close releases the io_uring instance of this Ring, if any. Afterwards, the
+// Ring falls back to reading files using [ReadFile].
+func (r *Ring) Close() error {
+	for _, m := range [][]byte{r.sqesMmap, r.cqRing, r.sqRing} {
+		if m != nil {
+			_ = unix.Munmap(m)
+		}
+	}
+	r.sqesMmap, r.cqRing, r.sqRing = nil, nil, nil
+	r.buffers, r.names, r.paths, r.nameOff, r.results = nil, nil, nil, nil, nil
+	if r.fd < 0 {
+		return nil
+	}
+	err := unix.Close(r.fd)
+	r.fd = -1
+	return err
+}
+
+// retire releases the io_uring instance after io_uring failed us, so that this
+// Ring permanently falls back to [ReadFile]; in particular, stale submission
+// queue entries never get submitted with a later batch. As the kernel might
+// still be processing already submitted entries referencing the read buffers
+// and names, these are kept alive for the lifetime of this Ring.
+func (r *Ring) retire() {
+	r.retired = [][]byte{r.buffers, r.names}
+	_ = r.Close()
+}
+
+// readFallback reads the specified file using [ReadFile] and yields it if
+// successful, returning false if the loop body wants to stop.
+func (r *Ring) readFallback(path string, yield func(string, []byte) bool) bool {
+	var ok bool
+	if r.spill, ok = ReadFile(path, r.spill); ok && !yield(path, r.spill) {
+		return false
+	}
+	return true
+}
+
+// setup creates and maps the io_uring instance, registers the direct
+// descriptor table, and checks that the required operations, including
+// opening into direct descriptors, are supported, returning true on success.
+func (r *Ring) setup() bool {
+	params := ioUringParams{flags: ioringSetupClamp}
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP,
+		uintptr(3*r.batch), uintptr(unsafe.Pointer(&params)), 0)
+	if errno != 0 {
+		return false
+	}
+	r.fd = int(fd)
+	if params.sqEntries < uint32(3*r.batch) {
+		r.batch = int(params.sqEntries / 3)
+	}
+
+	sqSize := int(params.sqOff.array + params.sqEntries*4)
+	cqSize := int(params.cqOff.cqes + params.cqEntries*uint32(unsafe.Sizeof(ioUringCqe{})))
+	if params.features&ioringFeatSingleMmap != 0 {
+		sqSize = max(sqSize, cqSize)
+	}
+	var err error
+	if r.sqRing, err = unix.Mmap(r.fd, ioringOffSqRing, sqSize,
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		return false
+	}
+	if params.features&ioringFeatSingleMmap == 0 {
+		if r.cqRing, err = unix.Mmap(r.fd, ioringOffCqRing, cqSize,
+			unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+			return false
+		}
+	}
+	if r.sqesMmap, err = unix.Mmap(r.fd, ioringOffSqes, int(params.sqEntries)*int(unsafe.Sizeof(ioUringSqe{})),
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		return false
+	}
+	sq := unsafe.Pointer(&r.sqRing[0])
+	cq := sq
+	if r.cqRing != nil {
+		cq = unsafe.Pointer(&r.cqRing[0])
+	}
+	r.sqTail = (*uint32)(unsafe.Add(sq, params.sqOff.tail))
+	r.sqMask = (*uint32)(unsafe.Add(sq, params.sqOff.ringMask))
+	r.sqArray = unsafe.Add(sq, params.sqOff.array)
+	r.sqes = unsafe.Pointer(&r.sqesMmap[0])
+	r.cqHead = (*uint32)(unsafe.Add(cq, params.cqOff.head))
+	r.cqTail = (*uint32)(unsafe.Add(cq, params.cqOff.tail))
+	r.cqMask = (*uint32)(unsafe.Add(cq, params.cqOff.ringMask))
+	r.cqes = unsafe.Add(cq, params.cqOff.cqes)
+
+	if !r.probe() {
+		return false
+	}
+	files := make([]int32, r.batch)
+	for i := range files {
+		files[i] = -1
+	}
+	if _, _, errno := unix.Syscall6(unix.SYS_IO_URING_REGISTER, uintptr(r.fd),
+		ioringRegisterFiles, uintptr(unsafe.Pointer(&files[0])), uintptr(len(files)), 0, 0); errno != 0 {
+		return false
+	}
+
+	r.buffers = make([]byte, r.batch*ringReadBufferSize)
+	r.paths = make([]string, 0, r.batch)
+	r.nameOff = make([]int, 0, r.batch)
+	r.results = make([]int32, r.batch)
+
+	// Finally check that opening into direct descriptors works, as this
+	// appeared only with kernel 5.15, by reading an always-present file.
+	r.queue("/proc/self/stat")
+	ok := r.submit() && r.results[0] > 0
+	r.paths = r.paths[:0]
+	return ok
+}
+
+// probe returns true if the io_uring instance supports the openat, read, and
+// close operations.
+func (r *Ring) probe() bool {
+	var probe ioUringProbe
+	if _, _, errno := unix.Syscall6(unix.SYS_IO_URING_REGISTER, uintptr(r.fd),
+		ioringRegisterProbe, uintptr(unsafe.Pointer(&probe)), ioringProbeOpsMaxSize, 0, 0); errno != 0 {
+		return false
+	}
+	for _, op := range []uint8{ioringOpOpenat, ioringOpRead, ioringOpClose} {
+		if op > probe.lastOp || probe.ops[op].flags&ioringOpSupported == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadFiles returns an iterator over the paths and contents of the files
+// specified by the paths iterator, reading the files in batches using
+// io_uring. Files are yielded in the order of the supplied paths; files that
+// cannot be read are silently skipped. Files larger than the per-file read
+// buffer of a few KiB are transparently re-read using [ReadFile].
+//
+// The contents yielded are only valid inside the loop body, as the buffers get
+// reused for reading the next batch of files.
+func (r *Ring) ReadFiles(paths iter.Seq[string]) iter.Seq2[string, []byte] {
+	return func(yield func(string, []byte) bool) {
+		r.paths = r.paths[:0]
+		for path := range paths {
+			if r.fd < 0 {
+				// either io_uring is unavailable or failed us midway.
+				if !r.readFallback(path, yield) {
+					return
+				}
+				continue
+			}
+			if !r.queue(path) {
+				continue
+			}
+			if len(r.paths) == r.batch && !r.flush(yield) {
+				return
+			}
+		}
+		r.flush(yield)
+	}
+}
+
+// queue adds the specified file path to the current batch, returning false if
+// the path cannot be passed to the kernel, such as when containing NUL.
+func (r *Ring) queue(path string) bool {
+	if len(path) >= unix.PathMax {
+		return false
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] == 0 {
+			return false
+		}
+	}
+	if len(r.paths) == 0 {
+		r.names = r.names[:0]
+		r.nameOff = r.nameOff[:0]
+	}
+	r.nameOff = append(r.nameOff, len(r.names))
+	r.names = append(append(r.names, path...), 0)
+	r.paths = append(r.paths, path)
+	return true
+}
+
+// flush reads the files of the current batch and yields them in order,
+// returning false if the loop body wants to stop.
+func (r *Ring) flush(yield func(string, []byte) bool) bool {
+	if len(r.paths) == 0 {
+		return true
+	}
+	if !r.submit() {
+		// Should io_uring unexpectedly fail us, permanently fall back to
+		// plain reading, starting with the files of this batch.
+		paths := r.paths
+		r.retire()
+		for _, path := range paths {
+			if !r.readFallback(path, yield) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, path := range r.paths {
+		res := int(r.results[i])
+		var contents []byte
+		switch {
+		case res < 0:
+			continue
+		case res < ringReadBufferSize:
+			contents = r.buffers[i*ringReadBufferSize : i*ringReadBufferSize+res]
+		default:
+			var ok bool
+			if r.spill, ok = ReadFile(path, r.spill); !ok {
+				continue
+			}
+			contents = r.spill
+		}
+		if !yield(path, contents) {
+			r.paths = r.paths[:0]
+			return false
+		}
+	}
+	r.paths = r.paths[:0]
+	return true
+}
+
+// submit submits a linked openat, read, and close operation for each file in
+// the current batch and waits for all of them to complete, setting the read
+// results of the files. It returns false if io_uring failed.
+func (r *Ring) submit() bool {
+	n := len(r.paths)
+	mask := *r.sqMask
+	tail := atomic.LoadUint32(r.sqTail)
+	names := unsafe.Pointer(unsafe.SliceData(r.names))
+	buffers := unsafe.Pointer(unsafe.SliceData(r.buffers))
+	for i := range n {
+		slot := uint32(i)
+		r.sqe(tail, mask, ioUringSqe{
+			opcode:    ioringOpOpenat,
+			flags:     iosqeIoLink,
+			fd:        unix.AT_FDCWD,
+			addr:      uint64(uintptr(unsafe.Add(names, r.nameOff[i]))),
+			opFlags:   unix.O_RDONLY,
+			userData:  uint64(i)<<ringOpBits | ringOpen,
+			fileIndex: slot + 1,
+		})
+		r.sqe(tail+1, mask, ioUringSqe{
+			opcode:   ioringOpRead,
+			flags:    iosqeFixedFile | iosqeIoHardlink,
+			fd:       int32(slot),
+			addr:     uint64(uintptr(unsafe.Add(buffers, i*ringReadBufferSize))),
+			len:      ringReadBufferSize,
+			userData: uint64(i)<<ringOpBits | ringRead,
+		})
+		r.sqe(tail+2, mask, ioUringSqe{
+			opcode:    ioringOpClose,
+			userData:  uint64(i)<<ringOpBits | ringClose,
+			fileIndex: slot + 1,
+		})
+		tail += 3
+	}
+	atomic.StoreUint32(r.sqTail, tail)
+
+	toSubmit := 3 * n
+	pending := 3 * n
+	for pending > 0 {
+		submitted, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd),
+			uintptr(toSubmit), 1, ioringEnterGetevents, 0, 0)
+		switch errno {
+		case 0:
+			toSubmit -= int(submitted)
+		case unix.EINTR, unix.EAGAIN, unix.EBUSY:
+		default:
+			return false
+		}
+		head := atomic.LoadUint32(r.cqHead)
+		cqTail := atomic.LoadUint32(r.cqTail)
+		cqMask := *r.cqMask
+		for ; head != cqTail; head++ {
+			cqe := (*ioUringCqe)(unsafe.Add(r.cqes, uintptr(head&cqMask)*unsafe.Sizeof(ioUringCqe{})))
+			// As a failing openat cancels the linked read, the read result
+			// tells whether a file could be read.
+			if cqe.userData&ringOpMask == ringRead {
+				r.results[cqe.userData>>ringOpBits] = cqe.res
+			}
+			pending--
+		}
+		atomic.StoreUint32(r.cqHead, head)
+	}
+	return true
+}
+
+// sqe places the specified submission queue entry at the given position of the
+// submission queue.
+func (r *Ring) sqe(pos uint32, mask uint32, sqe ioUringSqe) {
+	idx := pos & mask
+	*(*ioUringSqe)(unsafe.Add(r.sqes, uintptr(idx)*unsafe.Sizeof(ioUringSqe{}))) = sqe
+	*(*uint32)(unsafe.Add(r.sqArray, uintptr(idx)*4)) = idx
+}
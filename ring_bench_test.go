@@ -0,0 +1,77 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+/*
+
+go test -bench=RingReadFiles -run=^$ -benchmem
+
+goos: linux
+goarch: amd64
+pkg: github.com/thediveo/faf
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkRingReadFiles/faf.ReadFile                          4406            322773 ns/op               0 B/op          0 allocs/op
+BenchmarkRingReadFiles/faf.Ring.ReadFiles/batch=16           4554            255754 ns/op              14 B/op          0 allocs/op
+BenchmarkRingReadFiles/faf.Ring.ReadFiles/batch=64           4461            342656 ns/op              59 B/op          0 allocs/op
+BenchmarkRingReadFiles/faf.Ring.ReadFiles/batch=256          4803            246948 ns/op             220 B/op          0 allocs/op
+
+(reading the “stat” files of 61 processes per op)
+
+*/
+
+package faf_test
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/thediveo/faf"
+)
+
+// procStatPaths returns the paths of the “stat” files of all processes.
+func procStatPaths() []string {
+	var paths []string
+	for pid := range faf.PIDs("/proc") {
+		paths = append(paths, "/proc/"+strconv.FormatUint(pid, 10)+"/stat")
+	}
+	return paths
+}
+
+func BenchmarkRingReadFiles(b *testing.B) {
+	paths := procStatPaths()
+	b.Logf("reading %d procfs files per op", len(paths))
+	b.Run("faf.ReadFile", func(b *testing.B) {
+		buff := make([]byte, 0, 4096)
+		for n := 0; n < b.N; n++ {
+			for _, path := range paths {
+				buff, _ = faf.ReadFile(path, buff)
+			}
+		}
+	})
+	for _, batch := range []int{16, 64, 256} {
+		b.Run("faf.Ring.ReadFiles/batch="+strconv.Itoa(batch), func(b *testing.B) {
+			r := faf.NewRing(batch)
+			defer r.Close()
+			if !r.Uring() {
+				b.Skip("io_uring unavailable")
+			}
+			for n := 0; n < b.N; n++ {
+				for _, contents = range r.ReadFiles(slices.Values(paths)) {
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("io_uring-backed reading", func() {
+
+	var dir string
+	var names []string
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+
+		dir = GinkgoT().TempDir()
+		names = nil
+		for i := range 10 {
+			name := filepath.Join(dir, strconv.Itoa(i))
+			Expect(os.WriteFile(name, []byte("file "+strconv.Itoa(i)), 0644)).To(Succeed())
+			names = append(names, name)
+		}
+	})
+
+	readAll := func(r *Ring, paths []string) (readnames []string, contents []string) {
+		for path, b := range r.ReadFiles(slices.Values(paths)) {
+			readnames = append(readnames, path)
+			contents = append(contents, string(b))
+		}
+		return
+	}
+
+	DescribeTable("reads files in order, skipping unreadable ones",
+		func(batch int, fallback bool) {
+			r := NewRing(batch)
+			defer r.Close()
+			if fallback {
+				Expect(r.Close()).To(Succeed())
+				Expect(r.Uring()).To(BeFalse())
+			} else if !r.Uring() {
+				Skip("io_uring unavailable")
+			}
+
+			paths := slices.Clone(names)
+			paths = slices.Insert(paths, 5,
+				filepath.Join(dir, "non-existing"), dir, "LICENSE", "nul\x00name")
+			readnames, contents := readAll(r, paths)
+			Expect(readnames).To(Equal(slices.Insert(slices.Clone(names), 5, "LICENSE")))
+			for i, name := range readnames {
+				if name == "LICENSE" {
+					Expect(contents[i]).To(Equal(string(Successful(os.ReadFile("LICENSE")))))
+					continue
+				}
+				Expect(contents[i]).To(Equal("file " + filepath.Base(name)))
+			}
+			// reusing the ring
+			readnames, _ = readAll(r, names)
+			Expect(readnames).To(Equal(names))
+		},
+		Entry("single batch", 0, false),
+		Entry("multiple batches", 3, false),
+		Entry("single file batches", 1, false),
+		Entry("fallback", 0, true),
+	)
+
+	It("stops early", func() {
+		r := NewRing(4)
+		defer r.Close()
+		for _, fallback := range []bool{false, true} {
+			if fallback {
+				Expect(r.Close()).To(Succeed())
+			}
+			count := 0
+			for range r.ReadFiles(slices.Values(names)) {
+				count++
+				if count == 6 {
+					break
+				}
+			}
+			Expect(count).To(Equal(6))
+			readnames, _ := readAll(r, names)
+			Expect(readnames).To(Equal(names))
+		}
+	})
+
+	It("permanently falls back when io_uring fails midway", func() {
+		r := NewRing(3)
+		defer r.Close()
+		if !r.Uring() {
+			Skip("io_uring unavailable")
+		}
+		// Make io_uring_enter fail by swapping in a non-io_uring fd, after the
+		// first batch has been read successfully.
+		ringfd := r.fd
+		defer unix.Close(ringfd)
+		nullfd := Successful(unix.Open("/dev/null", unix.O_RDONLY|unix.O_CLOEXEC, 0))
+
+		var readnames, contents []string
+		for path, b := range r.ReadFiles(slices.Values(names)) {
+			readnames = append(readnames, path)
+			contents = append(contents, string(b))
+			if len(readnames) == 3 {
+				r.fd = nullfd
+			}
+		}
+		Expect(r.Uring()).To(BeFalse())
+		Expect(readnames).To(Equal(names))
+		for i := range names {
+			Expect(contents[i]).To(Equal("file " + strconv.Itoa(i)))
+		}
+		readnames, _ = readAll(r, names)
+		Expect(readnames).To(Equal(names))
+	})
+
+	It("reads procfs files", func() {
+		r := NewRing(0)
+		defer r.Close()
+		var paths []string
+		for pid := range PIDs("/proc") {
+			paths = append(paths, "/proc/"+strconv.FormatUint(pid, 10)+"/stat")
+		}
+		count := 0
+		for _, b := range r.ReadFiles(slices.Values(paths)) {
+			Expect(b).To(MatchRegexp(`^\d+ \(`))
+			count++
+		}
+		Expect(count).To(BeNumerically(">", 0))
+	})
+
+})
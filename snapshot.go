@@ -0,0 +1,192 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"bytes"
+	"iter"
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// DirSnapshot is a snapshot of the entries of a directory that, in contrast to
+// the directory entries yielded by [ReadDir], can be kept beyond the loop
+// body, such as for comparing the contents of a directory before and after.
+//
+// A DirSnapshot stores all entry names in a single contiguous name arena,
+// together with a compact table of inode numbers, types, and name offsets into
+// the arena. Taking a snapshot thus needs only O(1) heap allocations,
+// regardless of the number of directory entries, as opposed to O(n) when
+// converting all names into individual strings.
+type DirSnapshot struct {
+	names   []byte          // arena of all entry names, back to back.
+	entries []snapshotEntry // entries in directory order, or sorted by name.
+	sorted  bool            // entries are sorted by name.
+}
+
+// snapshotEntry is a single directory entry in a directory snapshot, with its
+// name stored in the name arena of the snapshot.
+type snapshotEntry struct {
+	ino     uint64
+	nameOff uint32
+	nameLen uint16 // names can exceed NAME_MAX, such as on CIFS, but not 64k.
+	typ     DirEntryType
+}
+
+// SnapshotDir returns a snapshot of the entries of the specified directory, or
+// nil if the directory cannot be read.
+//
+// In order to keep the number of heap allocations constant, SnapshotDir reads
+// the directory twice: first to determine the number of entries and the total
+// size of their names, and then to fill the exactly sized name arena and entry
+// table. Should the directory grow in between, the arena and table simply get
+// grown as necessary, at the expense of additional allocations.
+func SnapshotDir(name string, opts ...ReadDirOption) *DirSnapshot {
+	fd, errno := openat(unix.AT_FDCWD, name, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+	if errno != 0 {
+		return nil
+	}
+	defer unix.Close(fd)
+
+	count := 0
+	size := 0
+	if readDirFd(fd, 0, func(dirEntry DirEntry) bool {
+		count++
+		size += len(dirEntry.Name)
+		return true
+	}) != nil {
+		return nil
+	}
+	if _, err := unix.Seek(fd, 0, unix.SEEK_SET); err != nil {
+		return nil
+	}
+
+	s := &DirSnapshot{
+		names:   make([]byte, 0, size),
+		entries: make([]snapshotEntry, 0, count),
+	}
+	if readDirFd(fd, readDirFlags(opts), func(dirEntry DirEntry) bool {
+		s.add(dirEntry)
+		return true
+	}) != nil {
+		return nil
+	}
+	return s
+}
+
+// add adds the specified directory entry to this snapshot, copying its name
+// into the name arena. As the raw directory entries returned by getdents64
+// have a 16 bit record length, names always fit the 16 bit name length.
+func (s *DirSnapshot) add(dirEntry DirEntry) {
+	s.entries = append(s.entries, snapshotEntry{
+		ino:     dirEntry.Ino,
+		nameOff: uint32(len(s.names)),
+		nameLen: uint16(len(dirEntry.Name)),
+		typ:     dirEntry.Type,
+	})
+	s.names = append(s.names, dirEntry.Name...)
+}
+
+// Len returns the number of entries in this directory snapshot.
+func (s *DirSnapshot) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.entries)
+}
+
+// Entry returns the directory entry with the specified index, which must be in
+// the range [0, Len()). The Name field of the directory entry returned
+// references the name arena of this snapshot and thus must not be modified.
+//
+// Please note that the Off field of the directory entry returned is always
+// zero, as offset cookies are not snapshotted.
+func (s *DirSnapshot) Entry(idx int) DirEntry {
+	return s.entry(&s.entries[idx])
+}
+
+// entry returns the snapshot entry in form of a [DirEntry].
+func (s *DirSnapshot) entry(e *snapshotEntry) DirEntry {
+	return DirEntry{
+		Ino:  e.ino,
+		Name: s.name(e),
+		Type: e.typ,
+	}
+}
+
+// name returns the name of the snapshot entry.
+func (s *DirSnapshot) name(e *snapshotEntry) []byte {
+	end := e.nameOff + uint32(e.nameLen)
+	return s.names[e.nameOff:end:end]
+}
+
+// All returns an iterator over all entries in this directory snapshot, either
+// in the original directory order or sorted by name after calling
+// [DirSnapshot.Sort].
+func (s *DirSnapshot) All() iter.Seq[DirEntry] {
+	return func(yield func(DirEntry) bool) {
+		if s == nil {
+			return
+		}
+		for idx := range s.entries {
+			if !yield(s.entry(&s.entries[idx])) {
+				return
+			}
+		}
+	}
+}
+
+// Sort sorts the entries of this directory snapshot by name, in byte-wise
+// lexical order. Sorting doesn't allocate. Once sorted, [DirSnapshot.Lookup]
+// uses binary search instead of a linear search.
+func (s *DirSnapshot) Sort() {
+	if s == nil || s.sorted {
+		return
+	}
+	slices.SortFunc(s.entries, func(a, b snapshotEntry) int {
+		return bytes.Compare(s.name(&a), s.name(&b))
+	})
+	s.sorted = true
+}
+
+// Sorted returns true if the entries of this directory snapshot are sorted by
+// name.
+func (s *DirSnapshot) Sorted() bool { return s != nil && s.sorted }
+
+// Lookup returns the directory entry with the specified name and true, or
+// false if there is no such entry in this directory snapshot.
+func (s *DirSnapshot) Lookup(name []byte) (DirEntry, bool) {
+	if s == nil {
+		return DirEntry{}, false
+	}
+	if s.sorted {
+		idx, found := slices.BinarySearchFunc(s.entries, name, func(e snapshotEntry, name []byte) int {
+			return bytes.Compare(s.name(&e), name)
+		})
+		if !found {
+			return DirEntry{}, false
+		}
+		return s.entry(&s.entries[idx]), true
+	}
+	for idx := range s.entries {
+		if bytes.Equal(s.name(&s.entries[idx]), name) {
+			return s.entry(&s.entries[idx]), true
+		}
+	}
+	return DirEntry{}, false
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+)
+
+var _ = Describe("directory snapshots", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+
+		dir = GinkgoT().TempDir()
+		for idx := range 100 {
+			Expect(os.WriteFile(filepath.Join(dir, strconv.Itoa(idx)), nil, 0644)).To(Succeed())
+		}
+		Expect(os.Mkdir(filepath.Join(dir, "subdir"), 0755)).To(Succeed())
+	})
+
+	names := func(s *DirSnapshot) []string {
+		names := []string{}
+		for dentry := range s.All() {
+			names = append(names, string(dentry.Name))
+		}
+		return names
+	}
+
+	It("returns nil for non-existing directory", func() {
+		s := SnapshotDir(filepath.Join(dir, "non-existing"))
+		Expect(s).To(BeNil())
+		Expect(s.Len()).To(BeZero())
+		Expect(names(s)).To(BeEmpty())
+		Expect(s.Sorted()).To(BeFalse())
+		s.Sort()
+		_, ok := s.Lookup([]byte("0"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("takes a snapshot", func() {
+		s := SnapshotDir(dir)
+		Expect(s).NotTo(BeNil())
+		Expect(s.Len()).To(Equal(101))
+		Expect(s.Sorted()).To(BeFalse())
+
+		// make sure the snapshot doesn't reference any transient buffers.
+		for range ReadDir("./_testdata/foo") {
+		}
+
+		expected := []string{"subdir"}
+		for idx := range 100 {
+			expected = append(expected, strconv.Itoa(idx))
+		}
+		Expect(names(s)).To(ConsistOf(expected))
+
+		for idx := range s.Len() {
+			dentry := s.Entry(idx)
+			Expect(dentry.Ino).NotTo(BeZero())
+			Expect(dentry.IsDir()).To(Equal(string(dentry.Name) == "subdir"))
+		}
+
+		count := 0
+		for range s.All() {
+			count++
+			break
+		}
+		Expect(count).To(Equal(1))
+	})
+
+	It("sorts and looks up", func() {
+		s := SnapshotDir(dir)
+		Expect(s).NotTo(BeNil())
+
+		dentry, ok := s.Lookup([]byte("42"))
+		Expect(ok).To(BeTrue())
+		Expect(dentry.Name).To(Equal([]byte("42")))
+		Expect(dentry.IsRegular()).To(BeTrue())
+		_, ok = s.Lookup([]byte("666"))
+		Expect(ok).To(BeFalse())
+
+		s.Sort()
+		Expect(s.Sorted()).To(BeTrue())
+		Expect(slices.IsSorted(names(s))).To(BeTrue())
+
+		dentry, ok = s.Lookup([]byte("subdir"))
+		Expect(ok).To(BeTrue())
+		Expect(dentry.IsDir()).To(BeTrue())
+		_, ok = s.Lookup([]byte("666"))
+		Expect(ok).To(BeFalse())
+		s.Sort()
+	})
+
+	It("keeps names longer than NAME_MAX", func() {
+		longName := []byte(strings.Repeat("x", 300))
+		s := &DirSnapshot{}
+		s.add(DirEntry{Ino: 42, Name: longName, Type: DirEntryRegular})
+		s.add(DirEntry{Ino: 666, Name: []byte("foo"), Type: DirEntryDir})
+		Expect(s.Entry(0).Name).To(Equal(longName))
+		Expect(s.Entry(1).Name).To(Equal([]byte("foo")))
+		dentry, ok := s.Lookup(longName)
+		Expect(ok).To(BeTrue())
+		Expect(dentry.Ino).To(Equal(uint64(42)))
+	})
+
+	It("needs a constant number of allocations", func() {
+		small := testing.AllocsPerRun(10, func() {
+			_ = SnapshotDir("./_testdata/foo")
+		})
+		large := testing.AllocsPerRun(10, func() {
+			_ = SnapshotDir(dir)
+		})
+		// SnapshotDir reads the directory twice, getting a read buffer from
+		// the pool each time.
+		maxAllocs := 3 + poolMissAllocs(2)
+		Expect(small).To(BeNumerically("<=", maxAllocs))
+		Expect(large).To(BeNumerically("<=", maxAllocs))
+		if !raceEnabled {
+			Expect(large).To(Equal(small))
+		}
+		Expect(testing.AllocsPerRun(10, func() {
+			SnapshotDir(dir).Sort()
+		})).To(BeNumerically("<=", maxAllocs))
+	})
+
+})
@@ -0,0 +1,159 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"iter"
+
+	"golang.org/x/sys/unix"
+)
+
+// WalkOptions controls how [Walk] traverses a directory tree. The zero value
+// walks the full tree, crossing file system boundaries.
+type WalkOptions struct {
+	// MaxDepth limits the depth of the walk, where 1 yields only the entries
+	// of the root directory itself, 2 additionally the entries of the
+	// subdirectories of the root directory, and so on. Zero means unlimited
+	// depth.
+	MaxDepth int
+	// OneFilesystem restricts the walk to the file system the root directory
+	// is on; mount points are still yielded, but not descended into.
+	OneFilesystem bool
+}
+
+// Walker walks a directory tree, allowing the loop body to prune subtrees by
+// calling [Walker.SkipDir]. A Walker supports only a single walk in progress
+// at any time; use separate Walkers for concurrent or nested walks.
+type Walker struct {
+	root    string
+	opts    WalkOptions
+	skipDir bool // skip descending into the directory entry just yielded.
+}
+
+// NewWalker returns a new Walker for the directory tree rooted at root,
+// configured by opts; opts can be nil in order to walk the full directory tree.
+// Use [Walker.All] to start the walk.
+func NewWalker(root string, opts *WalkOptions) *Walker {
+	w := &Walker{root: root}
+	if opts != nil {
+		w.opts = *opts
+	}
+	return w
+}
+
+// SkipDir tells the walk in progress to not descend into the directory entry
+// that has just been yielded; it is meant to be called from inside the loop
+// body. Calling SkipDir on non-directory entries is a no-op.
+func (wk *Walker) SkipDir() { wk.skipDir = true }
+
+// Walk returns an iterator walking the directory tree rooted at root; see
+// [Walker.All] for details. opts can be nil in order to walk the full directory
+// tree. Use a [Walker] instead in order to prune subtrees.
+func Walk(root string, opts *WalkOptions) iter.Seq2[[]byte, DirEntry] {
+	return NewWalker(root, opts).All()
+}
+
+// All returns an iterator walking the directory tree in depth-first pre-order,
+// yielding the paths and directory entries of all files and directories below
+// the root, but not the root itself. The paths yielded are formed by joining
+// the root with the names of the directory entries using “/”. Subdirectories
+// are opened relative to their parent directories using openat, so the kernel
+// doesn't have to walk the full paths again and again. Symbolic links are
+// never followed. All silently skips directories that cannot be read. All
+// always resolves directory entries of unknown type, see also
+// [ResolveUnknownTypes].
+//
+// The loop body can prune subtrees by calling [Walker.SkipDir].
+//
+// Please note that the paths yielded are backed by a single path buffer that
+// gets reused throughout the walk, so they are only valid inside the loop
+// body. The same lifetime restriction applies to the Name fields of the
+// directory entries yielded, as with [ReadDir].
+func (wk *Walker) All() iter.Seq2[[]byte, DirEntry] {
+	return func(yield func([]byte, DirEntry) bool) {
+		root := wk.root
+		fd, errno := openat(unix.AT_FDCWD, root, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if errno != 0 {
+			return
+		}
+		defer unix.Close(fd)
+		w := walker{
+			ctl:   wk,
+			yield: yield,
+		}
+		if wk.opts.OneFilesystem {
+			var st unix.Stat_t
+			if unix.Fstat(fd, &st) != nil {
+				return
+			}
+			w.dev = st.Dev
+		}
+		w.path.Reset(root)
+		if !w.path.Ok() {
+			return
+		}
+		if root != "" && root[len(root)-1] == '/' {
+			w.path.Truncate(w.path.Len() - 1)
+		}
+		w.walk(fd, 1)
+	}
+}
+
+// walker keeps the state of a directory tree walk in progress.
+type walker struct {
+	path    PathBuf
+	ctl     *Walker
+	dev     uint64
+	yield   func([]byte, DirEntry) bool
+	stopped bool
+}
+
+// walk yields the entries of the directory referenced by fd, descending into
+// subdirectories as allowed by the walk options. It returns false when the
+// consumer stopped the walk.
+func (w *walker) walk(fd int, depth int) bool {
+	_ = readDirFd(fd, ResolveUnknownTypes, func(dirEntry DirEntry) bool {
+		pathLen := w.path.Len()
+		defer w.path.Truncate(pathLen)
+		if !w.path.Str("/").Append(dirEntry.Name).Ok() {
+			return true // skip entries with overlong paths.
+		}
+		w.ctl.skipDir = false
+		if !w.yield(w.path.Bytes(), dirEntry) {
+			w.stopped = true
+			return false
+		}
+		if !dirEntry.IsDir() || w.ctl.skipDir ||
+			(w.ctl.opts.MaxDepth > 0 && depth >= w.ctl.opts.MaxDepth) {
+			return true
+		}
+		subfd, errno := openatBytes(fd, dirEntry.Name,
+			unix.O_RDONLY|unix.O_CLOEXEC|unix.O_DIRECTORY|unix.O_NOFOLLOW, 0)
+		if errno != 0 {
+			return true
+		}
+		defer unix.Close(subfd)
+		if w.ctl.opts.OneFilesystem {
+			var st unix.Stat_t
+			if unix.Fstat(subfd, &st) != nil || st.Dev != w.dev {
+				return true
+			}
+		}
+		return w.walk(subfd, depth+1)
+	})
+	return !w.stopped
+}
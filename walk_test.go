@@ -0,0 +1,145 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+)
+
+var _ = Describe("walking directory trees", func() {
+
+	var root string
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+
+		root = GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(root, "a/b/c"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(root, "d"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "a/f"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "a/b/c/g"), nil, 0644)).To(Succeed())
+		Expect(os.Symlink("a", filepath.Join(root, "l"))).To(Succeed())
+	})
+
+	walk := func(root string, opts *WalkOptions) []string {
+		GinkgoHelper()
+		paths := []string{}
+		for path, dirEntry := range Walk(root, opts) {
+			Expect(filepath.Base(string(path))).To(Equal(string(dirEntry.Name)))
+			paths = append(paths, string(path))
+		}
+		return paths
+	}
+
+	It("walks the full tree without following symlinks", func() {
+		Expect(walk(root, nil)).To(ConsistOf(
+			root+"/a", root+"/a/b", root+"/a/b/c", root+"/a/b/c/g", root+"/a/f",
+			root+"/d", root+"/l"))
+		Expect(walk(root+"/", nil)).To(ConsistOf(
+			root+"/a", root+"/a/b", root+"/a/b/c", root+"/a/b/c/g", root+"/a/f",
+			root+"/d", root+"/l"))
+	})
+
+	It("walks in pre-order", func() {
+		paths := walk(root, nil)
+		idx := func(path string) int {
+			for i, p := range paths {
+				if p == path {
+					return i
+				}
+			}
+			return -1
+		}
+		Expect(idx(root + "/a")).To(BeNumerically("<", idx(root+"/a/b")))
+		Expect(idx(root + "/a/b")).To(BeNumerically("<", idx(root+"/a/b/c")))
+		Expect(idx(root + "/a/b/c")).To(BeNumerically("<", idx(root+"/a/b/c/g")))
+	})
+
+	It("returns nothing for a non-existing root", func() {
+		Expect(walk(root+"/non-existing", nil)).To(BeEmpty())
+	})
+
+	It("limits the depth", func() {
+		Expect(walk(root, &WalkOptions{MaxDepth: 1})).To(ConsistOf(
+			root+"/a", root+"/d", root+"/l"))
+		Expect(walk(root, &WalkOptions{MaxDepth: 2})).To(ConsistOf(
+			root+"/a", root+"/a/b", root+"/a/f", root+"/d", root+"/l"))
+	})
+
+	It("prunes subtrees", func() {
+		w := NewWalker(root, nil)
+		paths := []string{}
+		for path := range w.All() {
+			paths = append(paths, string(path))
+			if strings.HasSuffix(string(path), "/b") {
+				w.SkipDir()
+			}
+		}
+		Expect(paths).To(ConsistOf(
+			root+"/a", root+"/a/b", root+"/a/f", root+"/d", root+"/l"))
+	})
+
+	It("shares options between nested walks", func() {
+		opts := &WalkOptions{MaxDepth: 1}
+		outer := NewWalker(root, opts)
+		paths := []string{}
+		for path, dirEntry := range outer.All() {
+			paths = append(paths, string(path))
+			if string(dirEntry.Name) != "a" {
+				continue
+			}
+			inner := NewWalker(string(path), opts)
+			for path := range inner.All() {
+				paths = append(paths, string(path))
+				inner.SkipDir()
+			}
+		}
+		Expect(paths).To(ConsistOf(
+			root+"/a", root+"/a/b", root+"/a/f", root+"/d", root+"/l"))
+	})
+
+	It("stops early", func() {
+		count := 0
+		for range Walk(root, nil) {
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		Expect(count).To(Equal(2))
+	})
+
+	It("stays on the same file system", func() {
+		paths := walk("/", &WalkOptions{MaxDepth: 2})
+		Expect(paths).To(ContainElement("/proc"))
+		Expect(paths).To(ContainElement("/proc/self"))
+
+		paths = walk("/", &WalkOptions{MaxDepth: 2, OneFilesystem: true})
+		Expect(paths).To(ContainElement("/proc"))
+		Expect(paths).NotTo(ContainElement(HavePrefix("/proc/")))
+	})
+
+})
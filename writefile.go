@@ -0,0 +1,78 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// WriteFile writes the specified data to the named file using a single write
+// syscall, returning true if all data has been written. As kernel control
+// files in procfs, sysfs, and cgroupfs often need the whole value to be
+// written in one go, WriteFile never splits the data into multiple writes;
+// instead, a short write is considered a failure.
+//
+// In contrast to [os.WriteFile], WriteFile doesn't create the named file if it
+// doesn't exist, so mistyped control file names don't create stray files. An
+// existing regular file gets truncated. Similar to [ReadFile], WriteFile doesn't
+// allocate (unless the name is very long).
+func WriteFile(name string, data []byte) bool {
+	return WriteFileErrno(name, data) == 0
+}
+
+// WriteFileErrno works like [WriteFile], but returns the [unix.Errno] of the
+// failing syscall instead of a bare bool, or zero on success. A short write is
+// reported as EIO.
+func WriteFileErrno(name string, data []byte) unix.Errno {
+	fd, errno := openat(unix.AT_FDCWD, name, unix.O_WRONLY|unix.O_TRUNC|unix.O_CLOEXEC, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(fd)
+	// Issue the raw syscall, so that stack buffers don't escape to the heap,
+	// not even in race builds with the instrumented unix.Write.
+	var dp unsafe.Pointer
+	if len(data) > 0 {
+		dp = unsafe.Pointer(&data[0])
+	}
+	n, _, errno := unix.Syscall(unix.SYS_WRITE, uintptr(fd), uintptr(dp), uintptr(len(data)))
+	if errno != 0 {
+		return errno
+	}
+	if int(n) != len(data) {
+		return unix.EIO
+	}
+	return 0
+}
+
+// WriteUint writes the decimal representation of the specified unsigned number
+// to the named file, such as a PID into a cgroup's “cgroup.procs” file. It
+// formats the number into a stack buffer and then works like [WriteFile].
+func WriteUint(name string, num uint64) bool {
+	var buff [20]byte
+	return WriteFileErrno(name, AppendUint(buff[:0], num)) == 0
+}
+
+// WriteInt writes the decimal representation of the specified signed number to
+// the named file, such as a process' “oom_score_adj”. It formats the number
+// into a stack buffer and then works like [WriteFile].
+func WriteInt(name string, num int64) bool {
+	var buff [20]byte
+	return WriteFileErrno(name, AppendInt(buff[:0], num)) == 0
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package faf
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("WriteFile", func() {
+
+	var path string
+
+	BeforeEach(func() {
+		goodfds := Filedescriptors()
+		DeferCleanup(func() {
+			Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+		})
+
+		path = filepath.Join(GinkgoT().TempDir(), "foo")
+		Expect(os.WriteFile(path, []byte("some longer contents"), 0644)).To(Succeed())
+	})
+
+	It("writes and truncates", func() {
+		Expect(WriteFile(path, []byte("foobar"))).To(BeTrue())
+		Expect(Successful(os.ReadFile(path))).To(Equal([]byte("foobar")))
+	})
+
+	It("doesn't create files", func() {
+		nonexisting := filepath.Join(filepath.Dir(path), "non-existing")
+		Expect(WriteFile(nonexisting, []byte("foobar"))).To(BeFalse())
+		Expect(WriteFileErrno(nonexisting, []byte("foobar"))).To(Equal(unix.ENOENT))
+		Expect(nonexisting).NotTo(BeAnExistingFile())
+	})
+
+	It("reports errnos", func() {
+		Expect(WriteFileErrno(path, []byte("foobar"))).To(BeZero())
+		Expect(WriteFileErrno(filepath.Dir(path), []byte("foobar"))).To(Equal(unix.EISDIR))
+		Expect(WriteFileErrno("/proc/self/stat", []byte("foobar"))).NotTo(BeZero())
+	})
+
+	It("writes numbers", func() {
+		Expect(WriteUint(path, math.MaxUint64)).To(BeTrue())
+		Expect(Successful(os.ReadFile(path))).To(Equal([]byte("18446744073709551615")))
+		Expect(WriteInt(path, math.MinInt64)).To(BeTrue())
+		Expect(Successful(os.ReadFile(path))).To(Equal([]byte("-9223372036854775808")))
+		Expect(WriteUint(filepath.Dir(path), 42)).To(BeFalse())
+		Expect(WriteInt(filepath.Dir(path), -42)).To(BeFalse())
+	})
+
+	It("writes procfs control files", func() {
+		oomScoreAdj := Successful(strconv.ParseInt(
+			strings.TrimSpace(string(Successful(os.ReadFile("/proc/self/oom_score_adj")))), 10, 64))
+		Expect(WriteInt("/proc/self/oom_score_adj", oomScoreAdj)).To(BeTrue())
+		Expect(WriteInt("/proc/self/oom_score_adj", -10000)).To(BeFalse())
+	})
+
+	It("doesn't allocate", func() {
+		data := []byte("foobar")
+		Expect(testing.AllocsPerRun(100, func() {
+			_ = WriteFile(path, data)
+			_ = WriteUint(path, 1234567890)
+			_ = WriteInt(path, -1234567890)
+		})).To(BeZero())
+	})
+
+})